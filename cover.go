@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+func coverCmd(args []string) int {
+	flags := flag.NewFlagSet("cover", flag.ContinueOnError)
+	lcovPath := flags.String("lcov", "", "write the coverage to this lcov file")
+	htmlPath := flags.String("html", "", "write an HTML report to this file")
+	merge := flags.Bool("merge", false, "add the coverage already in the lcov file")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "golox cover: no files given")
+		return 2
+	}
+	if *merge && *lcovPath == "" {
+		fmt.Fprintln(os.Stderr, "golox cover: --merge needs an --lcov file")
+		return 2
+	}
+
+	cov := interpreter.NewCoverage()
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+			continue
+		}
+		interp := interpreter.New(
+			interpreter.WithCoverage(cov, path),
+			interpreter.WithLoader(interpreter.FSLoader{FS: os.DirFS(filepath.Dir(path))}),
+		)
+		if err := interp.Exec(string(src)); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			code = 1
+		}
+	}
+
+	if *merge {
+		f, err := os.Open(*lcovPath)
+		if err == nil {
+			prev, err := interpreter.ReadLcov(f)
+			f.Close()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", *lcovPath, err)
+				return 1
+			}
+			cov.Merge(prev)
+		} else if !os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	if *lcovPath != "" {
+		if err := writeFile(*lcovPath, cov.WriteLcov); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if *htmlPath != "" {
+		if err := writeFile(*htmlPath, cov.WriteHTML); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	lines, linesHit, branches, branchesHit := cov.Stats()
+	fmt.Fprintf(os.Stderr, "coverage: lines %d/%d (%s), branches %d/%d (%s)\n",
+		linesHit, lines, percent(linesHit, lines), branchesHit, branches, percent(branchesHit, branches))
+	return code
+}
+
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func percent(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
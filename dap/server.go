@@ -0,0 +1,419 @@
+// Package dap implements a Debug Adapter Protocol server, letting
+// editors launch a Lox program under the interpreter debugger.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jrouviere/golox/internal/wire"
+	"github.com/jrouviere/golox/interpreter"
+)
+
+// Lox programs run on a single thread
+const threadID = 1
+
+type request struct {
+	Seq       int             `json:"seq"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+type Server struct {
+	in *bufio.Reader
+
+	// guards out and seq, events are sent from the program goroutine
+	wmu sync.Mutex
+	out io.Writer
+	seq int
+
+	mu          sync.Mutex
+	program     string
+	stopOnEntry bool
+	breakpoints []int
+	debugger    *interpreter.Debugger
+	// set while the program is suspended
+	stopped *interpreter.Stopped
+	// scopes of the stopped program, by variables reference
+	scopes      map[int]interpreter.Scope
+	terminating bool
+	entry       bool
+
+	resume chan interpreter.Action
+	// closed when the program is done
+	done chan struct{}
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:     bufio.NewReader(in),
+		out:    out,
+		resume: make(chan interpreter.Action),
+	}
+}
+
+// Serve handles requests until the client disconnects or closes the
+// input stream
+func (s *Server) Serve() error {
+	for {
+		body, err := wire.Read(s.in)
+		if err == io.EOF {
+			return s.disconnect()
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("dap: invalid request: %v", err)
+		}
+
+		res, err := s.handle(&req)
+		if err := s.respond(&req, res, err); err != nil {
+			return err
+		}
+
+		switch req.Command {
+		case "initialize":
+			if err := s.event("initialized", nil); err != nil {
+				return err
+			}
+		case "disconnect", "terminate":
+			return nil
+		}
+	}
+}
+
+func (s *Server) send(v interface{}) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+
+	s.seq++
+	switch v := v.(type) {
+	case *response:
+		v.Seq = s.seq
+	case *event:
+		v.Seq = s.seq
+	}
+	return wire.Write(s.out, v)
+}
+
+func (s *Server) respond(req *request, body interface{}, err error) error {
+	res := &response{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Command:    req.Command,
+		Success:    err == nil,
+		Body:       body,
+	}
+	if err != nil {
+		res.Message = err.Error()
+	}
+	return s.send(res)
+}
+
+func (s *Server) event(name string, body interface{}) error {
+	return s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+func (s *Server) handle(req *request) (interface{}, error) {
+	switch req.Command {
+	case "initialize":
+		return map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+		}, nil
+
+	case "launch":
+		var args struct {
+			Program     string `json:"program"`
+			StopOnEntry bool   `json:"stopOnEntry"`
+		}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		if args.Program == "" {
+			return nil, errors.New("missing program to launch")
+		}
+		s.mu.Lock()
+		s.program = args.Program
+		s.stopOnEntry = args.StopOnEntry
+		s.mu.Unlock()
+		return nil, nil
+
+	case "setBreakpoints":
+		var args struct {
+			Breakpoints []struct {
+				Line int `json:"line"`
+			} `json:"breakpoints"`
+		}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+
+		var lines []int
+		var bps []map[string]interface{}
+		for _, bp := range args.Breakpoints {
+			lines = append(lines, bp.Line)
+			bps = append(bps, map[string]interface{}{"verified": true, "line": bp.Line})
+		}
+		s.mu.Lock()
+		s.breakpoints = lines
+		if s.debugger != nil {
+			s.debugger.SetBreakpoints(lines...)
+		}
+		s.mu.Unlock()
+		return map[string]interface{}{"breakpoints": bps}, nil
+
+	case "configurationDone":
+		return nil, s.start()
+
+	case "threads":
+		return map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": threadID, "name": "main"}},
+		}, nil
+
+	case "stackTrace":
+		return s.stackTrace()
+	case "scopes":
+		var args struct {
+			FrameID int `json:"frameId"`
+		}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.frameScopes(args.FrameID)
+	case "variables":
+		var args struct {
+			VariablesReference int `json:"variablesReference"`
+		}
+		if err := json.Unmarshal(req.Arguments, &args); err != nil {
+			return nil, err
+		}
+		return s.variables(args.VariablesReference)
+
+	case "continue":
+		s.resumeWith(interpreter.Continue)
+		return map[string]interface{}{"allThreadsContinued": true}, nil
+	case "next":
+		s.resumeWith(interpreter.StepOver)
+		return nil, nil
+	case "stepIn":
+		s.resumeWith(interpreter.StepIn)
+		return nil, nil
+	case "stepOut":
+		s.resumeWith(interpreter.StepOut)
+		return nil, nil
+	case "pause":
+		s.mu.Lock()
+		if s.debugger != nil {
+			s.debugger.Pause()
+		}
+		s.mu.Unlock()
+		return nil, nil
+
+	case "disconnect", "terminate":
+		return nil, s.disconnect()
+	}
+	return nil, fmt.Errorf("unsupported command: %s", req.Command)
+}
+
+// start runs the launched program on its own goroutine
+func (s *Server) start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.program == "" {
+		return errors.New("no program launched")
+	}
+	if s.done != nil {
+		return errors.New("program already started")
+	}
+	src, err := os.ReadFile(s.program)
+	if err != nil {
+		return err
+	}
+
+	s.debugger = interpreter.NewDebugger(s.onStop)
+	s.debugger.SetBreakpoints(s.breakpoints...)
+	if s.stopOnEntry {
+		s.entry = true
+		s.debugger.Pause()
+	}
+	s.done = make(chan struct{})
+
+	interp := interpreter.New(
+		interpreter.WithOutput(output{s}),
+		interpreter.WithDebugger(s.debugger),
+		interpreter.WithLoader(interpreter.FSLoader{FS: os.DirFS(filepath.Dir(s.program))}),
+	)
+	go func() {
+		defer close(s.done)
+
+		code := 0
+		if err := interp.Exec(string(src)); err != nil {
+			if err != interpreter.ErrAborted {
+				s.event("output", map[string]interface{}{"category": "stderr", "output": err.Error() + "\n"})
+			}
+			code = 1
+		}
+		s.event("exited", map[string]interface{}{"exitCode": code})
+		s.event("terminated", nil)
+	}()
+	return nil
+}
+
+// onStop is called on the program goroutine, it waits for the client
+// to resume the execution
+func (s *Server) onStop(st *interpreter.Stopped) interpreter.Action {
+	s.mu.Lock()
+	if s.terminating {
+		s.mu.Unlock()
+		return interpreter.Abort
+	}
+	s.stopped = st
+	s.scopes = make(map[int]interpreter.Scope)
+	reason := string(st.Reason)
+	if s.entry {
+		reason = "entry"
+		s.entry = false
+	}
+	s.mu.Unlock()
+
+	s.event("stopped", map[string]interface{}{
+		"reason":            reason,
+		"threadId":          threadID,
+		"allThreadsStopped": true,
+	})
+	return <-s.resume
+}
+
+func (s *Server) resumeWith(action interpreter.Action) {
+	s.mu.Lock()
+	stopped := s.stopped != nil
+	s.stopped = nil
+	s.mu.Unlock()
+
+	if stopped {
+		s.resume <- action
+	}
+}
+
+// disconnect aborts the program if it's still running
+func (s *Server) disconnect() error {
+	s.mu.Lock()
+	s.terminating = true
+	if s.debugger != nil {
+		s.debugger.Pause()
+	}
+	done := s.done
+	s.mu.Unlock()
+
+	s.resumeWith(interpreter.Abort)
+	if done != nil {
+		<-done
+	}
+	return nil
+}
+
+func (s *Server) stackTrace() (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped == nil {
+		return nil, errors.New("program is not stopped")
+	}
+	frames := []map[string]interface{}{}
+	for id, f := range s.stopped.Frames {
+		frames = append(frames, map[string]interface{}{
+			"id":     id,
+			"name":   f.Function,
+			"line":   f.Line,
+			"column": 1,
+			"source": map[string]string{
+				"name": filepath.Base(s.program),
+				"path": s.program,
+			},
+		})
+	}
+	return map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+func (s *Server) frameScopes(frameID int) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped == nil {
+		return nil, errors.New("program is not stopped")
+	}
+	if frameID < 0 || frameID >= len(s.stopped.Frames) {
+		return nil, fmt.Errorf("unknown frame %d", frameID)
+	}
+
+	scopes := []map[string]interface{}{}
+	for _, sc := range s.stopped.Frames[frameID].Scopes {
+		ref := len(s.scopes) + 1
+		s.scopes[ref] = sc
+		scopes = append(scopes, map[string]interface{}{
+			"name":               sc.Name,
+			"variablesReference": ref,
+			"expensive":          false,
+		})
+	}
+	return map[string]interface{}{"scopes": scopes}, nil
+}
+
+func (s *Server) variables(ref int) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, ok := s.scopes[ref]
+	if !ok {
+		return nil, fmt.Errorf("unknown variables reference %d", ref)
+	}
+	vars := []map[string]interface{}{}
+	for _, v := range sc.Vars {
+		vars = append(vars, map[string]interface{}{
+			"name":               v.Name,
+			"value":              v.Value,
+			"variablesReference": 0,
+		})
+	}
+	return map[string]interface{}{"variables": vars}, nil
+}
+
+// output forwards what the program prints as output events
+type output struct {
+	s *Server
+}
+
+func (o output) Write(p []byte) (int, error) {
+	err := o.s.event("output", map[string]interface{}{"category": "stdout", "output": string(p)})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
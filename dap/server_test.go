@@ -0,0 +1,245 @@
+package dap_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jrouviere/golox/dap"
+)
+
+type message struct {
+	Type       string          `json:"type"`
+	Event      string          `json:"event"`
+	Command    string          `json:"command"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client drives a Server the way an editor would
+type client struct {
+	t      *testing.T
+	w      io.WriteCloser
+	r      *bufio.Reader
+	seq    int
+	events []message
+	// what the program printed so far
+	printed strings.Builder
+	done    chan error
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	c := &client{t: t, w: inW, r: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		err := dap.NewServer(inR, outW).Serve()
+		outW.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+func (c *client) receive() message {
+	length := 0
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "Content-Length: ") {
+			length, _ = strconv.Atoi(strings.TrimPrefix(line, "Content-Length: "))
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		c.t.Fatal(err)
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// request sends a command and decodes the body of its response in res,
+// events received meanwhile are queued
+func (c *client) request(command string, args, res interface{}) {
+	c.seq++
+	body, _ := json.Marshal(map[string]interface{}{
+		"seq": c.seq, "type": "request", "command": command, "arguments": args,
+	})
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatal(err)
+	}
+
+	for {
+		msg := c.receive()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		if msg.RequestSeq != c.seq || msg.Command != command {
+			c.t.Fatalf("%s: unexpected response %+v", command, msg)
+		}
+		if !msg.Success {
+			c.t.Fatalf("%s: %s", command, msg.Message)
+		}
+		if res != nil {
+			if err := json.Unmarshal(msg.Body, res); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return
+	}
+}
+
+// event waits for the given event, skipping the output events and
+// returning its body
+func (c *client) event(name string, body interface{}) {
+	for {
+		var msg message
+		if len(c.events) > 0 {
+			msg = c.events[0]
+			c.events = c.events[1:]
+		} else {
+			msg = c.receive()
+		}
+		if msg.Event == "output" {
+			c.output(msg)
+			continue
+		}
+		if msg.Event != name {
+			c.t.Fatalf("expected %s event, got %+v", name, msg)
+		}
+		if body != nil {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return
+	}
+}
+
+func (c *client) output(msg message) {
+	var body struct {
+		Output string `json:"output"`
+	}
+	json.Unmarshal(msg.Body, &body)
+	c.printed.WriteString(body.Output)
+}
+
+const program = `var total = 0;
+fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+total = add(total, 2);
+print total;
+`
+
+func TestSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.lox")
+	if err := os.WriteFile(path, []byte(program), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(t)
+
+	c.request("initialize", map[string]interface{}{"adapterID": "golox"}, nil)
+	c.event("initialized", nil)
+	c.request("launch", map[string]interface{}{"program": path, "stopOnEntry": true}, nil)
+	c.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": path},
+		"breakpoints": []map[string]int{{"line": 4}},
+	}, nil)
+	c.request("configurationDone", nil, nil)
+
+	var stopped struct {
+		Reason string `json:"reason"`
+	}
+	c.event("stopped", &stopped)
+	if stopped.Reason != "entry" {
+		t.Errorf("expected to stop on entry, got %q", stopped.Reason)
+	}
+
+	c.request("continue", map[string]int{"threadId": 1}, nil)
+	c.event("stopped", &stopped)
+	if stopped.Reason != "breakpoint" {
+		t.Errorf("expected to stop on the breakpoint, got %q", stopped.Reason)
+	}
+
+	var trace struct {
+		StackFrames []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+			Line int    `json:"line"`
+		} `json:"stackFrames"`
+	}
+	c.request("stackTrace", map[string]int{"threadId": 1}, &trace)
+	if len(trace.StackFrames) != 2 || trace.StackFrames[0].Name != "<fn add>" || trace.StackFrames[0].Line != 4 ||
+		trace.StackFrames[1].Line != 6 {
+		t.Fatalf("unexpected stack trace: %+v", trace)
+	}
+
+	var scopes struct {
+		Scopes []struct {
+			Name               string `json:"name"`
+			VariablesReference int    `json:"variablesReference"`
+		} `json:"scopes"`
+	}
+	c.request("scopes", map[string]int{"frameId": trace.StackFrames[0].ID}, &scopes)
+	if len(scopes.Scopes) != 3 || scopes.Scopes[1].Name != "Enclosing" {
+		t.Fatalf("unexpected scopes: %+v", scopes)
+	}
+
+	var vars struct {
+		Variables []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"variables"`
+	}
+	c.request("variables", map[string]int{"variablesReference": scopes.Scopes[1].VariablesReference}, &vars)
+	got := fmt.Sprint(vars.Variables)
+	if got != "[{a 0} {b 2}]" {
+		t.Errorf("unexpected variables: %s", got)
+	}
+
+	c.request("stepOut", map[string]int{"threadId": 1}, nil)
+	c.event("stopped", &stopped)
+	c.request("stackTrace", map[string]int{"threadId": 1}, &trace)
+	if len(trace.StackFrames) != 1 || trace.StackFrames[0].Line != 7 {
+		t.Errorf("unexpected stack trace after step out: %+v", trace)
+	}
+
+	c.request("continue", map[string]int{"threadId": 1}, nil)
+	var exited struct {
+		ExitCode int `json:"exitCode"`
+	}
+	c.event("exited", &exited)
+	c.event("terminated", nil)
+	if exited.ExitCode != 0 {
+		t.Errorf("unexpected exit code %d", exited.ExitCode)
+	}
+	if c.printed.String() != "2\n" {
+		t.Errorf("unexpected output %q", c.printed.String())
+	}
+
+	c.request("disconnect", nil, nil)
+	c.w.Close()
+	if err := <-c.done; err != nil {
+		t.Fatal(err)
+	}
+}
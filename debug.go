@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jrouviere/golox/dap"
+	"github.com/jrouviere/golox/interpreter"
+)
+
+const debugHelp = `commands:
+  break N, b N      set a breakpoint on line N
+  clear N           remove the breakpoint on line N
+  continue, c       run until the next breakpoint
+  step, s           step to the next line, entering calls
+  next, n           step to the next line, over calls
+  out, o            run until the current function returns
+  stack, bt         print the call stack
+  vars [F], v [F]   print the variables visible from frame F (default 0)
+  list, l           print the source around the current line
+  quit, q           abort the program
+an empty line repeats the previous command
+`
+
+func debugCmd(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	useDAP := flags.Bool("dap", false, "serve the Debug Adapter Protocol on stdin/stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *useDAP {
+		if err := dap.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "golox debug: expected one file")
+		return 2
+	}
+	path := flags.Arg(0)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	cli := &debugCLI{
+		lines:       strings.Split(string(src), "\n"),
+		in:          bufio.NewScanner(os.Stdin),
+		out:         os.Stdout,
+		breakpoints: make(map[int]bool),
+	}
+	cli.debugger = interpreter.NewDebugger(cli.onStop)
+	cli.debugger.Pause()
+
+	interp := interpreter.New(
+		interpreter.WithDebugger(cli.debugger),
+		interpreter.WithLoader(interpreter.FSLoader{FS: os.DirFS(filepath.Dir(path))}),
+	)
+	if err := interp.Exec(string(src)); err != nil {
+		if err != interpreter.ErrAborted {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return 1
+	}
+	return 0
+}
+
+type debugCLI struct {
+	debugger    *interpreter.Debugger
+	lines       []string
+	in          *bufio.Scanner
+	out         io.Writer
+	breakpoints map[int]bool
+	last        string
+}
+
+// onStop reads commands until one resumes the execution
+func (c *debugCLI) onStop(st *interpreter.Stopped) interpreter.Action {
+	fmt.Fprintf(c.out, "stopped at line %d (%s)\n", st.Line, st.Reason)
+	c.printLine(st.Line, true)
+
+	for {
+		fmt.Fprint(c.out, "(golox) ")
+		if !c.in.Scan() {
+			return interpreter.Abort
+		}
+		line := strings.TrimSpace(c.in.Text())
+		if line == "" {
+			line = c.last
+		}
+		c.last = line
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "break", "b", "clear":
+			n, err := lineArg(fields)
+			if err != nil {
+				fmt.Fprintln(c.out, err)
+				continue
+			}
+			c.breakpoints[n] = fields[0] != "clear"
+			var lines []int
+			for l, set := range c.breakpoints {
+				if set {
+					lines = append(lines, l)
+				}
+			}
+			sort.Ints(lines)
+			c.debugger.SetBreakpoints(lines...)
+			fmt.Fprintf(c.out, "breakpoints: %v\n", lines)
+		case "continue", "c":
+			return interpreter.Continue
+		case "step", "s":
+			return interpreter.StepIn
+		case "next", "n":
+			return interpreter.StepOver
+		case "out", "o":
+			return interpreter.StepOut
+		case "stack", "bt":
+			for i, f := range st.Frames {
+				fmt.Fprintf(c.out, "#%d %s, line %d\n", i, f.Function, f.Line)
+			}
+		case "vars", "v":
+			frame := 0
+			if len(fields) > 1 {
+				n, err := strconv.Atoi(fields[1])
+				if err != nil || n < 0 || n >= len(st.Frames) {
+					fmt.Fprintf(c.out, "invalid frame %q\n", fields[1])
+					continue
+				}
+				frame = n
+			}
+			for _, sc := range st.Frames[frame].Scopes {
+				fmt.Fprintf(c.out, "%s:\n", sc.Name)
+				for _, v := range sc.Vars {
+					fmt.Fprintf(c.out, "  %s = %s\n", v.Name, v.Value)
+				}
+			}
+		case "list", "l":
+			for n := st.Line - 3; n <= st.Line+3; n++ {
+				c.printLine(n, n == st.Line)
+			}
+		case "quit", "q":
+			return interpreter.Abort
+		case "help", "h":
+			fmt.Fprint(c.out, debugHelp)
+		default:
+			fmt.Fprintf(c.out, "unknown command %q, try help\n", fields[0])
+		}
+	}
+}
+
+func (c *debugCLI) printLine(n int, current bool) {
+	if n < 1 || n > len(c.lines) {
+		return
+	}
+	marker := " "
+	if current {
+		marker = ">"
+	}
+	if c.breakpoints[n] {
+		marker = "*" + marker
+	} else {
+		marker = " " + marker
+	}
+	fmt.Fprintf(c.out, "%s%4d  %s\n", marker, n, c.lines[n-1])
+}
+
+func lineArg(fields []string) (int, error) {
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("usage: %s LINE", fields[0])
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid line %q", fields[1])
+	}
+	return n, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jrouviere/golox/format"
+)
+
+func fmtCmd(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	check := flags.Bool("check", false, "don't write files, print a diff and fail if they are not formatted")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "golox fmt: no files given")
+		return 2
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+			continue
+		}
+		res, err := format.Source(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			code = 1
+			continue
+		}
+		if bytes.Equal(src, res) {
+			continue
+		}
+
+		if *check {
+			fmt.Printf("--- %s\n+++ %s (formatted)\n", path, path)
+			fmt.Print(diff(string(src), string(res)))
+			code = 1
+			continue
+		}
+		if err := os.WriteFile(path, res, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// diff returns a line by line diff between a and b, based on their
+// longest common subsequence
+func diff(a, b string) string {
+	x := strings.SplitAfter(a, "\n")
+	y := strings.SplitAfter(b, "\n")
+
+	// lcs[i][j] is the length of the lcs of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	line := func(prefix, s string) {
+		out.WriteString(prefix + strings.TrimSuffix(s, "\n") + "\n")
+	}
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			line(fmt.Sprintf("-%d: ", i+1), x[i])
+			i++
+		default:
+			line(fmt.Sprintf("+%d: ", j+1), y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		line(fmt.Sprintf("-%d: ", i+1), x[i])
+	}
+	for ; j < len(y); j++ {
+		line(fmt.Sprintf("+%d: ", j+1), y[j])
+	}
+	return out.String()
+}
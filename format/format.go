@@ -0,0 +1,401 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// Source parses src and prints it back in the canonical Lox style:
+// two spaces indentation, opening braces on the same line, one space
+// around binary operators and at most one blank line between statements.
+// Comments are kept next to the token they follow. A comment written
+// inside a statement ends its line, the statement goes on with one more
+// level of indentation.
+func Source(src []byte) ([]byte, error) {
+	scanner := parser.NewScanner(string(src))
+	tokens, err := scanner.ScanWithComments()
+	if err != nil {
+		return nil, err
+	}
+	stmts, err := parser.New(tokens).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &printer{
+		comments: scanner.Comments(),
+		atStart:  true,
+	}
+	p.list(stmts, tokens[len(tokens)-1].Line+1)
+	if !p.atStart {
+		p.b.WriteString("\n")
+	}
+	return []byte(p.b.String()), nil
+}
+
+type printer struct {
+	b        strings.Builder
+	comments []*parser.Comment
+	indent   int
+
+	// nothing has been written yet
+	atStart bool
+	// source line of the last statement or comment printed
+	lastLine int
+	// line of the closing brace of the block being printed, a comment
+	// on that line follows the brace
+	closing int
+	// written before the next token, unless a comment ends the line
+	sep string
+}
+
+// list prints stmts one per line, followed by the remaining comments
+// found before line until
+func (p *printer) list(stmts []parser.Stmt, until int) {
+	first := true
+	for idx, s := range stmts {
+		p.commentsBefore(s.Line(), &first)
+		p.newline(s.Line(), first)
+		first = false
+
+		p.stmt(s)
+		p.lastLine = endLine(s)
+		// a comment follows the last statement of its line
+		if idx == len(stmts)-1 || stmts[idx+1].Line() > p.lastLine {
+			p.trailing(p.lastLine)
+		}
+	}
+	p.commentsBefore(until, &first)
+}
+
+// newline starts a new indented line, keeping a single blank line if
+// there was at least one in the source
+func (p *printer) newline(srcLine int, first bool) {
+	if !p.atStart {
+		p.b.WriteString("\n")
+		if !first && srcLine > p.lastLine+1 {
+			p.b.WriteString("\n")
+		}
+	}
+	p.atStart = false
+	p.sep = ""
+	p.b.WriteString(strings.Repeat("  ", p.indent))
+}
+
+func (p *printer) commentsBefore(line int, first *bool) {
+	for len(p.comments) > 0 && p.comments[0].Line < line {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		p.newline(c.Line, *first)
+		*first = false
+		p.b.WriteString(c.Text)
+		p.lastLine = c.Line
+	}
+}
+
+// trailing prints the comment written at the end of line, if any, and
+// tells whether there was one
+func (p *printer) trailing(line int) bool {
+	if line == p.closing {
+		return false
+	}
+	if len(p.comments) > 0 && p.comments[0].Line == line && p.comments[0].Trailing {
+		p.b.WriteString(" " + p.comments[0].Text)
+		p.comments = p.comments[1:]
+		return true
+	}
+	return false
+}
+
+// inside prints the comments left before line, where the statement
+// being printed continues. A comment ends its line, the statement goes
+// on with one more level of indentation. It tells whether there was any.
+func (p *printer) inside(line int) bool {
+	indent := "\n" + strings.Repeat("  ", p.indent+1)
+	n := 0
+	for len(p.comments) > 0 && p.comments[0].Line < line {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		if n == 0 && c.Trailing {
+			p.b.WriteString(" " + c.Text)
+		} else {
+			p.b.WriteString(indent + c.Text)
+		}
+		n++
+	}
+	if n > 0 {
+		p.b.WriteString(indent)
+		p.sep = ""
+	}
+	return n > 0
+}
+
+// text writes s after the pending separator
+func (p *printer) text(s string) {
+	p.b.WriteString(p.sep + s)
+	p.sep = ""
+}
+
+// word writes a keyword followed by a space
+func (p *printer) word(s string) {
+	p.text(s)
+	p.sep = " "
+}
+
+// token writes s, found on line in the source, after the comments
+// written before it
+func (p *printer) token(s string, line int) {
+	p.inside(line)
+	p.text(s)
+}
+
+// op writes a binary operator found on line, surrounded by spaces
+func (p *printer) op(s string, line int) {
+	p.sep = " "
+	p.token(s, line)
+	p.sep = " "
+}
+
+// continued writes keyword, continuing a statement after branch with
+// next: on the same line after a block, unless a comment ends it, and
+// on a new line otherwise
+func (p *printer) continued(keyword string, branch, next parser.Stmt) {
+	_, block := branch.(*parser.Block)
+	end := endLine(branch)
+	if (end < next.Line() && p.trailing(end)) || !block {
+		p.b.WriteString("\n" + strings.Repeat("  ", p.indent))
+	} else {
+		p.sep = " "
+	}
+	p.word(keyword)
+}
+
+func (p *printer) stmt(s parser.Stmt) {
+	switch s := s.(type) {
+	case *parser.PrintStmt:
+		p.word("print")
+		p.expr(s.Value)
+		p.text(";")
+	case *parser.ReturnStmt:
+		if s.Value == nil {
+			p.text("return;")
+		} else {
+			p.word("return")
+			p.expr(s.Value)
+			p.text(";")
+		}
+	case *parser.ThrowStmt:
+		p.word("throw")
+		p.expr(s.Value)
+		p.text(";")
+	case *parser.ExprStmt:
+		p.expr(s.Value)
+		p.text(";")
+	case *parser.VarDecl:
+		p.word("var")
+		p.token(s.Name.Lexeme, s.Name.Line)
+		if s.Init != nil {
+			p.op("=", s.Name.Line)
+			p.expr(s.Init)
+		}
+		p.text(";")
+	case *parser.ImportStmt:
+		p.word("import")
+		p.token(s.Path.Lexeme, s.Path.Line)
+		p.op("as", s.Name.Line)
+		p.token(s.Name.Lexeme, s.Name.Line)
+		p.text(";")
+	case *parser.FunStmt:
+		p.word("fun")
+		p.token(s.Name.Lexeme, s.Name.Line)
+		p.text("(")
+		for idx, param := range s.Params {
+			if idx > 0 {
+				p.word(",")
+			}
+			p.token(param.Lexeme, param.Line)
+		}
+		p.word(")")
+		p.body(s.Body)
+	case *parser.Block:
+		p.block(s)
+	case *parser.IfStmt:
+		p.word("if")
+		p.text("(")
+		p.expr(s.Expr)
+		p.word(")")
+		p.body(s.ThenBrch)
+		if s.ElseBrch != nil {
+			p.continued("else", s.ThenBrch, s.ElseBrch)
+			p.body(s.ElseBrch)
+		}
+	case *parser.WhileStmt:
+		p.word("while")
+		p.text("(")
+		p.expr(s.Expr)
+		p.word(")")
+		p.body(s.Body)
+	case *parser.ForStmt:
+		p.word("for")
+		p.text("(")
+		if s.Init != nil {
+			p.stmt(s.Init)
+		} else {
+			p.text(";")
+		}
+		if s.Cond != nil {
+			p.sep = " "
+			p.expr(s.Cond)
+		}
+		p.text(";")
+		if s.Incr != nil {
+			p.sep = " "
+			p.expr(s.Incr)
+		}
+		p.word(")")
+		p.body(s.Body)
+	case *parser.TryStmt:
+		p.word("try")
+		p.body(s.Body)
+		last := s.Body
+		if s.CatchBody != nil {
+			p.continued("catch ("+s.CatchName.Lexeme+")", last, s.CatchBody)
+			p.body(s.CatchBody)
+			last = s.CatchBody
+		}
+		if s.FinallyBody != nil {
+			p.continued("finally", last, s.FinallyBody)
+			p.body(s.FinallyBody)
+		}
+	default:
+		panic(fmt.Sprintf("format: unexpected statement %T", s))
+	}
+}
+
+// body prints the body of a control statement, blocks open on the
+// same line and single statements stay on the same line, unless a
+// comment ends the header
+func (p *printer) body(s parser.Stmt) {
+	if b, ok := s.(*parser.Block); ok {
+		p.block(b)
+		return
+	}
+	if p.inside(s.Line()) {
+		p.indent++
+		defer func() { p.indent-- }()
+	}
+	p.stmt(s)
+}
+
+func (p *printer) block(b *parser.Block) {
+	closing := p.closing
+	p.closing = b.Rbrace.Line
+	defer func() { p.closing = closing }()
+
+	p.text("{")
+	// comments between the header and the brace follow the brace, a
+	// comment on the line of the closing brace follows that one
+	last := b.Lbrace.Line
+	if last == b.Rbrace.Line {
+		last--
+	}
+	n := 0
+	for len(p.comments) > 0 && p.comments[0].Line <= last {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		if n == 0 {
+			p.b.WriteString(" " + c.Text)
+		} else {
+			p.b.WriteString("\n" + strings.Repeat("  ", p.indent+1) + c.Text)
+		}
+		n++
+	}
+	p.lastLine = b.Lbrace.Line
+
+	if len(b.Statements) == 0 && n == 0 && (len(p.comments) == 0 || p.comments[0].Line >= b.Rbrace.Line) {
+		p.text("}")
+		return
+	}
+
+	p.indent++
+	p.list(b.Statements, b.Rbrace.Line)
+	p.indent--
+
+	p.b.WriteString("\n" + strings.Repeat("  ", p.indent) + "}")
+	p.lastLine = b.Rbrace.Line
+}
+
+func (p *printer) expr(e parser.Expr) {
+	switch e := e.(type) {
+	case *parser.BinaryExpr:
+		p.expr(e.Left)
+		p.op(e.Op.Lexeme, e.Op.Line)
+		p.expr(e.Right)
+	case *parser.Logical:
+		p.expr(e.Left)
+		p.op(e.Operator.Lexeme, e.Operator.Line)
+		p.expr(e.Right)
+	case *parser.UnaryExpr:
+		p.token(e.Op.Lexeme, e.Op.Line)
+		p.expr(e.Right)
+	case *parser.LiteralExpr:
+		p.token(e.Value.Lexeme, e.Value.Line)
+	case *parser.GroupingExpr:
+		p.text("(")
+		p.expr(e.Expr)
+		p.text(")")
+	case *parser.Variable:
+		p.token(e.Name.Lexeme, e.Name.Line)
+	case *parser.Assign:
+		p.token(e.Name.Lexeme, e.Name.Line)
+		p.op("=", e.Name.Line)
+		p.expr(e.Value)
+	case *parser.Call:
+		p.expr(e.Callee)
+		p.text("(")
+		for idx, a := range e.Args {
+			if idx > 0 {
+				p.word(",")
+			}
+			p.expr(a)
+		}
+		p.token(")", e.Paren.Line)
+	case *parser.GetExpr:
+		p.expr(e.Object)
+		p.token("."+e.Name.Lexeme, e.Name.Line)
+	case *parser.SetExpr:
+		p.expr(e.Object)
+		p.token("."+e.Name.Lexeme, e.Name.Line)
+		p.op("=", e.Name.Line)
+		p.expr(e.Value)
+	default:
+		panic(fmt.Sprintf("format: unexpected expression %T", e))
+	}
+}
+
+// endLine returns the last source line covered by n
+func endLine(n parser.Node) int {
+	end := n.Line()
+	parser.Inspect(n, func(c parser.Node) bool {
+		switch c := c.(type) {
+		case nil:
+			return false
+		case *parser.Block:
+			if c.Rbrace.Line > end {
+				end = c.Rbrace.Line
+			}
+		case *parser.Call:
+			if c.Paren.Line > end {
+				end = c.Paren.Line
+			}
+		}
+		if c.Line() > end {
+			end = c.Line()
+		}
+		return true
+	})
+	return end
+}
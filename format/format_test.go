@@ -0,0 +1,133 @@
+package format_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jrouviere/golox/format"
+	"github.com/jrouviere/golox/interpreter"
+)
+
+func TestSource(t *testing.T) {
+	src, err := os.ReadFile("testdata/messy.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile("testdata/messy.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := format.Source(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(res, expected) {
+		t.Errorf("unexpected result:\n%s", res)
+	}
+
+	again, err := format.Source(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(res, again) {
+		t.Errorf("formatting is not idempotent:\n%s", again)
+	}
+}
+
+// a comment inside a statement ends the line, the statement goes on
+// one level deeper
+func TestSourceCommentInside(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		want string
+	}{
+		{"print add(1, // one\n2);", "print add(1, // one\n  2);\n"},
+		{"print add(\n  1, // one\n  // two\n  2\n);", "print add(1, // one\n  // two\n  2);\n"},
+		{"var x = 1 +\n// two\n2;", "var x = 1 +\n  // two\n  2;\n"},
+		{"if (x) // then\nprint 1;", "if (x) // then\n  print 1;\n"},
+		{"if (x) print 1;\nelse // otherwise\nprint 2;", "if (x) print 1;\nelse // otherwise\n  print 2;\n"},
+		{"while (a) // cond\n{\n  a = false;\n}", "while (a) { // cond\n  a = false;\n}\n"},
+		{"while (a) // cond\n{}", "while (a) { // cond\n}\n"},
+		{"fun f(a, // first\nb) {}", "fun f(a, // first\n  b) {}\n"},
+		{"{\n  if (a and // left\n    b) print 1 // value\n  ;\n}", "{\n  if (a and // left\n    b) print 1; // value\n}\n"},
+	} {
+		res, err := format.Source([]byte(tc.src))
+		if err != nil {
+			t.Errorf("%q: %v", tc.src, err)
+			continue
+		}
+		if string(res) != tc.want {
+			t.Errorf("%q: got\n%s\nexpected\n%s", tc.src, res, tc.want)
+			continue
+		}
+		again, err := format.Source(res)
+		if err != nil || !bytes.Equal(res, again) {
+			t.Errorf("%q: formatting is not idempotent: %q, %v", tc.src, again, err)
+		}
+	}
+}
+
+// formatting the conformance tests must never change what they print
+func TestRoundTrip(t *testing.T) {
+	// these print line numbers, which formatting is free to move
+	printsLines := map[string]bool{
+		"runtime_error.lox": true,
+	}
+
+	files, err := filepath.Glob("../interpreter/testdata/*/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range files {
+		path := path
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			if printsLines[filepath.Base(path)] {
+				t.Skip("output depends on line numbers")
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			formatted, err := format.Source(src)
+			if err != nil {
+				t.Skip("not a valid program:", err)
+			}
+
+			out1, err1 := run(string(src))
+			out2, err2 := run(string(formatted))
+			if out1 != out2 {
+				t.Errorf("output changed\n--- before:\n%s\n--- after:\n%s", out1, out2)
+			}
+			if errMsg(err1) != errMsg(err2) {
+				t.Errorf("error changed\nbefore: %v\nafter:  %v", err1, err2)
+			}
+		})
+	}
+}
+
+func run(src string) (string, error) {
+	var out bytes.Buffer
+	interp := interpreter.New(
+		interpreter.WithOutput(&out),
+		interpreter.WithLoader(interpreter.FSLoader{FS: os.DirFS("../interpreter/testdata")}),
+	)
+	err := interp.Exec(src)
+	return out.String(), err
+}
+
+// errMsg ignores the line of runtime errors, formatting can move code
+func errMsg(err error) string {
+	var rerr *interpreter.RuntimeError
+	if errors.As(err, &rerr) {
+		return rerr.Msg
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
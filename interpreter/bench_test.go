@@ -0,0 +1,94 @@
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+const fibSrc = `fun fib(n) {
+  if (n <= 1) return n;
+  return fib(n - 2) + fib(n - 1);
+}
+fib(15);
+`
+
+// locals declared and read in nested blocks and loops
+const localsSrc = `fun sum(n) {
+  var total = 0;
+  for (var i = 0; i < n; i = i + 1) {
+    var sq = i * i;
+    {
+      var half = sq / 2;
+      total = total + half;
+    }
+  }
+  return total;
+}
+sum(2000);
+`
+
+// benchmark runs src again and again in the same interpreter, the
+// functions it declares are replaced each time
+func benchmark(b *testing.B, src string) {
+	interp := interpreter.New()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := interp.Exec(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmark(b, fibSrc)
+}
+
+func BenchmarkLocals(b *testing.B) {
+	benchmark(b, localsSrc)
+}
+
+// the script is only scanned and parsed once
+func BenchmarkFibProgram(b *testing.B) {
+	prog, err := interpreter.New().Compile(fibSrc)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := interpreter.New().ExecProgram(prog); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// forking the interpreter after the prelude ran, compared to running
+// the prelude again for each script
+func BenchmarkFork(b *testing.B) {
+	parent := interpreter.New()
+	if err := parent.Exec(fibSrc); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := parent.Fork().Exec("fib(2);"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNoFork(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		interp := interpreter.New()
+		if err := interp.Exec(fibSrc); err != nil {
+			b.Fatal(err)
+		}
+		if err := interp.Exec("fib(2);"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// CallContext is given to a Callable by the interpreter calling it. It
+// is only valid during the call, and must not be used from another
+// goroutine. The host calls functions with Interpreter.Call, the zero
+// CallContext has no interpreter to run Lox code in.
+type CallContext struct {
+	interp *Interpreter
+	// closing parenthesis of the call, nil when called by the host
+	paren *parser.Token
+}
+
+// Call calls fn, a function defined by a script, from the host. It
+// runs in the globals of i and like Exec it must not be called while i
+// is already running.
+func (i *Interpreter) Call(fn Value, args ...Value) (Value, error) {
+	return CallContext{interp: i}.Call(fn, args...)
+}
+
+func errNoInterpreter(fn interface{}) error {
+	return &RuntimeError{Msg: fmt.Sprintf("cannot call %v without an interpreter, use Interpreter.Call", fn)}
+}
+
+// Interpreter returns the interpreter making the call
+func (c CallContext) Interpreter() *Interpreter {
+	return c.interp
+}
+
+// Context returns the context given to ExecContext, or the background
+// context. Natives doing long or blocking work should stop when it is
+// done.
+func (c CallContext) Context() context.Context {
+	if c.interp == nil || c.interp.ctx == nil {
+		return context.Background()
+	}
+	return c.interp.ctx
+}
+
+// Output returns where print statements write, nowhere without an
+// interpreter
+func (c CallContext) Output() io.Writer {
+	if c.interp == nil {
+		return io.Discard
+	}
+	return c.interp.out
+}
+
+// Line returns the line of the call site, 0 if unknown
+func (c CallContext) Line() int {
+	if c.paren == nil {
+		return 0
+	}
+	return c.paren.Line
+}
+
+// Errorf returns a runtime error reported at the call site
+func (c CallContext) Errorf(format string, args ...interface{}) error {
+	return &RuntimeError{Msg: fmt.Sprintf(format, args...), Line: c.Line()}
+}
+
+// Call calls fn, typically a function passed as argument to a native,
+// the way a Lox call expression would: a fn that isn't callable or a
+// wrong number of arguments gives a runtime error, not a panic
+func (c CallContext) Call(fn Value, args ...Value) (Value, error) {
+	if c.interp == nil {
+		return Value{}, errNoInterpreter(fn)
+	}
+	callable, ok := fn.obj.(Callable)
+	if !ok {
+		return Value{}, c.Errorf("can only call functions and classes, got %s", typeName(fn))
+	}
+	if callable.Arity() != len(args) {
+		return Value{}, c.Errorf("expected %d arguments but got %d", callable.Arity(), len(args))
+	}
+	paren := c.paren
+	if paren == nil {
+		paren = &parser.Token{}
+	}
+	return c.interp.call(callable, args, paren)
+}
@@ -0,0 +1,112 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+// apply is a host native calling back the function it receives
+type apply struct{}
+
+func (apply) Arity() int {
+	return 2
+}
+
+func (apply) Call(c interpreter.CallContext, args []interpreter.Value) (interpreter.Value, error) {
+	if err := c.Context().Err(); err != nil {
+		return interpreter.Value{}, c.Errorf("apply: %v", err)
+	}
+	fmt.Fprintf(c.Output(), "apply, line %d\n", c.Line())
+	return c.Call(args[0], args[1])
+}
+
+type ctxKey struct{}
+
+func TestCallContext(t *testing.T) {
+	var out bytes.Buffer
+	interp := interpreter.New(interpreter.WithOutput(&out))
+	interp.Globals().Define("apply", interpreter.Object(apply{}))
+	interp.Globals().Define("user", interpreter.Reflect(func(ctx context.Context, greeting string) string {
+		return greeting + " " + ctx.Value(ctxKey{}).(string)
+	}))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "Ada")
+	err := interp.ExecContext(ctx, `
+		fun twice(n) { return n * 2; }
+		print apply(twice, 21);
+		print apply(user, "hello");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "apply, line 3\n42\napply, line 4\nhello Ada\n"; out.String() != expected {
+		t.Errorf("got %q, expected %q", out.String(), expected)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for src, msg := range map[string]string{
+		"apply(1, 2);":                        "can only call functions and classes, got number, line 1",
+		"\napply(clock, 2);":                  "expected 0 arguments but got 1, line 2",
+		"fun f(x) { throw x; }\napply(f, 1);": "uncaught exception: 1, line 1",
+	} {
+		err := interp.Exec(src)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%q: got error %v, expected %s", src, err, msg)
+		}
+	}
+	err = interp.ExecContext(cancelled, "apply(clock, 1);")
+	if err == nil || err.Error() != "runtime error: apply: context canceled, line 1" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestInterpreterCall(t *testing.T) {
+	interp := interpreter.New()
+	// functions only see their locals and the globals, there are no
+	// closures
+	err := interp.Exec(`
+		var n = 0;
+		fun inc(by) {
+			n = n + by;
+			return n;
+		}
+		var count = inc;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := interp.Globals().Get("count")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []float64{2, 4} {
+		v, err := interp.Call(count, interpreter.Number(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.AsNumber() != expected {
+			t.Errorf("got %v, expected %v", v, expected)
+		}
+	}
+
+	if _, err := interp.Call(count); err == nil || err.Error() != "runtime error: expected 1 arguments but got 0" {
+		t.Errorf("got error %v", err)
+	}
+	if _, err := interp.Call(interpreter.Number(1)); err == nil {
+		t.Error("calling a number succeeded")
+	}
+
+	// a zero CallContext can't run Lox code, but doesn't panic
+	fn := count.AsObject().(interpreter.Callable)
+	_, err = fn.Call(interpreter.CallContext{}, []interpreter.Value{interpreter.Number(1)})
+	if err == nil || err.Error() != "runtime error: cannot call <fn inc> without an interpreter, use Interpreter.Call" {
+		t.Errorf("got error %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+package interpreter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// Coverage counts the lines and branches executed by programs. The
+// same Coverage can be given to several interpreters in turn, and
+// coverage written by previous runs merged with ReadLcov and Merge.
+// It can't be shared between interpreters running concurrently.
+//
+// A line is counted each time its first statement is executed. The
+// branches are the then and else paths of an if, entering the body
+// of a loop or leaving it, and the short-circuit of and/or evaluating
+// or skipping their right operand.
+type Coverage struct {
+	files map[string]*fileCoverage
+
+	// instrumentation of the registered programs
+	lines    map[parser.Stmt]lineRef
+	branches map[parser.Node]lineRef
+}
+
+type fileCoverage struct {
+	name     string
+	source   string
+	lines    map[int]int
+	branches map[branchKey]int
+}
+
+// branchKey identifies a branch, block numbers the branch points of a
+// line in source order
+type branchKey struct {
+	line, block, branch int
+}
+
+type lineRef struct {
+	file  *fileCoverage
+	line  int
+	block int
+}
+
+// branches of a branch point
+const (
+	// then branch of an if, body of a loop, right operand of a logical
+	branchTaken = 0
+	// else branch or no else, loop exit, short-circuit
+	branchNotTaken = 1
+)
+
+func NewCoverage() *Coverage {
+	return &Coverage{
+		files:    make(map[string]*fileCoverage),
+		lines:    make(map[parser.Stmt]lineRef),
+		branches: make(map[parser.Node]lineRef),
+	}
+}
+
+// WithCoverage records in c the coverage of the programs executed,
+// under the name file. Imported modules are recorded under their path
+// joined to the directory of file.
+func WithCoverage(c *Coverage, file string) Option {
+	return func(i *Interpreter) {
+		i.coverage = c
+		i.coverFile = file
+	}
+}
+
+func (c *Coverage) file(name string) *fileCoverage {
+	f, ok := c.files[name]
+	if !ok {
+		f = &fileCoverage{
+			name:     name,
+			lines:    make(map[int]int),
+			branches: make(map[branchKey]int),
+		}
+		c.files[name] = f
+	}
+	return f
+}
+
+// register makes the lines and branches of stmts known, so the ones
+// never executed are reported too
+func (c *Coverage) register(name, source string, stmts []parser.Stmt) {
+	f := c.file(name)
+	f.source = source
+
+	blocks := make(map[int]int)
+	seen := make(map[int]bool)
+	for _, s := range stmts {
+		parser.Inspect(s, func(n parser.Node) bool {
+			switch n := n.(type) {
+			case nil, *parser.Block:
+				return true
+			case *parser.IfStmt, *parser.WhileStmt, *parser.ForStmt, *parser.Logical:
+				ref := lineRef{file: f, line: n.Line(), block: blocks[n.Line()]}
+				blocks[n.Line()]++
+				c.branches[n] = ref
+				for _, b := range []int{branchTaken, branchNotTaken} {
+					k := branchKey{ref.line, ref.block, b}
+					f.branches[k] += 0
+				}
+			}
+
+			if s, ok := n.(parser.Stmt); ok {
+				if !seen[s.Line()] {
+					seen[s.Line()] = true
+					f.lines[s.Line()] += 0
+					c.lines[s] = lineRef{file: f, line: s.Line()}
+				}
+			}
+			return true
+		})
+	}
+}
+
+func (c *Coverage) statement(s parser.Stmt) {
+	if ref, ok := c.lines[s]; ok {
+		ref.file.lines[ref.line]++
+	}
+}
+
+func (c *Coverage) branch(n parser.Node, branch int) {
+	if ref, ok := c.branches[n]; ok {
+		ref.file.branches[branchKey{ref.line, ref.block, branch}]++
+	}
+}
+
+// Merge adds the counts of other to c
+func (c *Coverage) Merge(other *Coverage) {
+	for name, of := range other.files {
+		f := c.file(name)
+		if f.source == "" {
+			f.source = of.source
+		}
+		for l, n := range of.lines {
+			f.lines[l] += n
+		}
+		for k, n := range of.branches {
+			f.branches[k] += n
+		}
+	}
+}
+
+// Stats returns the number of lines and branches found and executed
+func (c *Coverage) Stats() (lines, linesHit, branches, branchesHit int) {
+	for _, f := range c.files {
+		for _, n := range f.lines {
+			lines++
+			if n > 0 {
+				linesHit++
+			}
+		}
+		for _, n := range f.branches {
+			branches++
+			if n > 0 {
+				branchesHit++
+			}
+		}
+	}
+	return
+}
+
+func (c *Coverage) sortedFiles() []*fileCoverage {
+	var files []*fileCoverage
+	for _, f := range c.files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	return files
+}
+
+func (f *fileCoverage) sortedLines() []int {
+	var lines []int
+	for l := range f.lines {
+		lines = append(lines, l)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+func (f *fileCoverage) sortedBranches() []branchKey {
+	var keys []branchKey
+	for k := range f.branches {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.line != b.line {
+			return a.line < b.line
+		}
+		if a.block != b.block {
+			return a.block < b.block
+		}
+		return a.branch < b.branch
+	})
+	return keys
+}
+
+// WriteLcov writes the coverage in the lcov tracefile format
+func (c *Coverage) WriteLcov(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range c.sortedFiles() {
+		fmt.Fprintf(bw, "TN:\nSF:%s\n", f.name)
+
+		hit := 0
+		keys := f.sortedBranches()
+		for _, k := range keys {
+			taken := strconv.Itoa(f.branches[k])
+			// '-' tells the branch point itself was never reached
+			if f.branches[branchKey{k.line, k.block, branchTaken}]+f.branches[branchKey{k.line, k.block, branchNotTaken}] == 0 {
+				taken = "-"
+			}
+			if f.branches[k] > 0 {
+				hit++
+			}
+			fmt.Fprintf(bw, "BRDA:%d,%d,%d,%s\n", k.line, k.block, k.branch, taken)
+		}
+		fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", len(keys), hit)
+
+		hit = 0
+		lines := f.sortedLines()
+		for _, l := range lines {
+			if f.lines[l] > 0 {
+				hit++
+			}
+			fmt.Fprintf(bw, "DA:%d,%d\n", l, f.lines[l])
+		}
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", len(lines), hit)
+	}
+	return bw.Flush()
+}
+
+// ReadLcov reads coverage written by WriteLcov, to be merged with the
+// coverage of a new run
+func ReadLcov(r io.Reader) (*Coverage, error) {
+	c := NewCoverage()
+	var f *fileCoverage
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		kind, value, _ := strings.Cut(line, ":")
+
+		var fields []int
+		switch kind {
+		case "SF":
+			f = c.file(value)
+			continue
+		case "DA", "BRDA":
+			if f == nil {
+				return nil, fmt.Errorf("lcov line %d: %s outside of a file record", n, kind)
+			}
+			for _, v := range strings.Split(value, ",") {
+				if v == "-" {
+					v = "0"
+				}
+				x, err := strconv.Atoi(v)
+				if err != nil {
+					return nil, fmt.Errorf("lcov line %d: %v", n, err)
+				}
+				fields = append(fields, x)
+			}
+		case "end_of_record":
+			f = nil
+			continue
+		default:
+			// TN, summaries and records not produced by WriteLcov
+			continue
+		}
+
+		switch {
+		case kind == "DA" && len(fields) >= 2:
+			f.lines[fields[0]] += fields[1]
+		case kind == "BRDA" && len(fields) == 4:
+			f.branches[branchKey{fields[0], fields[1], fields[2]}] += fields[3]
+		default:
+			return nil, fmt.Errorf("lcov line %d: invalid %s record", n, kind)
+		}
+	}
+	return c, sc.Err()
+}
+
+// moduleFile is the name modules are recorded under
+func (i *Interpreter) moduleFile(modPath string) string {
+	return filepath.Join(filepath.Dir(i.coverFile), filepath.FromSlash(modPath))
+}
@@ -0,0 +1,123 @@
+package interpreter
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+)
+
+var coverageTmpl = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Lox coverage</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0 0.5em; white-space: pre; }
+td.num, td.hits, td.branches { text-align: right; color: #666; }
+tr.hit td.src { background: #dfd; }
+tr.missed td.src { background: #fdd; }
+tr.partial td.src { background: #ffd; }
+</style>
+</head>
+<body>
+<h1>Lox coverage</h1>
+<p>lines {{.LinesHit}}/{{.Lines}}, branches {{.BranchesHit}}/{{.Branches}}</p>
+<ul>{{range .Files}}<li><a href="#{{.ID}}">{{.Name}}</a> lines {{.LinesHit}}/{{.Lines}}, branches {{.BranchesHit}}/{{.Branches}}</li>{{end}}</ul>
+{{range .Files}}
+<h2 id="{{.ID}}">{{.Name}}</h2>
+<table>
+{{range .Rows}}<tr class="{{.Class}}"><td class="num">{{.Num}}</td><td class="hits">{{.Hits}}</td><td class="branches">{{.Branches}}</td><td class="src">{{.Source}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+type htmlReport struct {
+	htmlCounts
+	Files []htmlFile
+}
+
+type htmlCounts struct {
+	Lines, LinesHit, Branches, BranchesHit int
+}
+
+type htmlFile struct {
+	htmlCounts
+	ID   string
+	Name string
+	Rows []htmlRow
+}
+
+type htmlRow struct {
+	Num      int
+	Hits     string
+	Branches string
+	Class    string
+	Source   string
+}
+
+// WriteHTML writes a standalone HTML page showing the hit count of each
+// line and the branches taken. Files merged from an lcov report are
+// read from disk.
+func (c *Coverage) WriteHTML(w io.Writer) error {
+	var report htmlReport
+	report.Lines, report.LinesHit, report.Branches, report.BranchesHit = c.Stats()
+
+	for idx, f := range c.sortedFiles() {
+		hf := htmlFile{ID: fmt.Sprintf("file%d", idx), Name: f.name}
+
+		src := f.source
+		if src == "" {
+			if b, err := os.ReadFile(f.name); err == nil {
+				src = string(b)
+			}
+		}
+		lines := strings.Split(strings.TrimSuffix(src, "\n"), "\n")
+		for _, l := range f.sortedLines() {
+			for len(lines) < l {
+				lines = append(lines, "")
+			}
+		}
+
+		// taken and total branches by line
+		branches := make(map[int][2]int)
+		for k, n := range f.branches {
+			b := branches[k.line]
+			b[1]++
+			hf.Branches++
+			if n > 0 {
+				b[0]++
+				hf.BranchesHit++
+			}
+			branches[k.line] = b
+		}
+
+		for idx, text := range lines {
+			num := idx + 1
+			row := htmlRow{Num: num, Source: text}
+			if n, ok := f.lines[num]; ok {
+				hf.Lines++
+				row.Hits = fmt.Sprintf("%dx", n)
+				row.Class = "missed"
+				if n > 0 {
+					hf.LinesHit++
+					row.Class = "hit"
+				}
+			}
+			if b, ok := branches[num]; ok {
+				row.Branches = fmt.Sprintf("%d/%d", b[0], b[1])
+				if b[0] < b[1] && row.Class == "hit" {
+					row.Class = "partial"
+				}
+			}
+			hf.Rows = append(hf.Rows, row)
+		}
+		report.Files = append(report.Files, hf)
+	}
+	return coverageTmpl.Execute(w, report)
+}
@@ -0,0 +1,147 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+const coverSrc = `fun check(n) {
+  if (n > 1 and n < 10) {
+    return "small";
+  } else {
+    return "other";
+  }
+}
+var i = 0;
+while (i < 2) i = i + 1;
+print check(i);
+fun unused() {
+  print "never";
+}
+`
+
+func cover(t *testing.T, c *interpreter.Coverage, src string) {
+	var out bytes.Buffer
+	interp := interpreter.New(interpreter.WithOutput(&out), interpreter.WithCoverage(c, "rules.lox"))
+	if err := interp.Exec(src); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lcov(t *testing.T, c *interpreter.Coverage) string {
+	var b bytes.Buffer
+	if err := c.WriteLcov(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestCoverage(t *testing.T) {
+	c := interpreter.NewCoverage()
+	cover(t, c, coverSrc)
+
+	expected := `TN:
+SF:rules.lox
+BRDA:2,0,0,1
+BRDA:2,0,1,0
+BRDA:2,1,0,1
+BRDA:2,1,1,0
+BRDA:9,0,0,2
+BRDA:9,0,1,1
+BRF:6
+BRH:4
+DA:1,1
+DA:2,1
+DA:3,1
+DA:5,0
+DA:8,1
+DA:9,1
+DA:10,1
+DA:11,1
+DA:12,0
+LF:9
+LH:7
+end_of_record
+`
+	if got := lcov(t, c); got != expected {
+		t.Errorf("unexpected lcov:\n%s", got)
+	}
+
+	lines, hit, branches, taken := c.Stats()
+	if lines != 9 || hit != 7 || branches != 6 || taken != 4 {
+		t.Errorf("unexpected stats: %d/%d lines, %d/%d branches", hit, lines, taken, branches)
+	}
+}
+
+func TestCoverageMerge(t *testing.T) {
+	first := interpreter.NewCoverage()
+	cover(t, first, coverSrc)
+
+	// a previous run, read back from its report
+	prev, err := interpreter.ReadLcov(strings.NewReader(lcov(t, first)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := interpreter.NewCoverage()
+	cover(t, c, strings.Replace(coverSrc, "while (i < 2)", "while (i < 20)", 1))
+	c.Merge(prev)
+
+	got := lcov(t, c)
+	for _, line := range []string{
+		// both if paths are now covered
+		"BRDA:2,0,0,1\n", "BRDA:2,0,1,1\n",
+		"DA:3,1\n", "DA:5,1\n", "DA:2,2\n",
+		"BRDA:9,0,0,22\n",
+		"DA:12,0\n",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("missing %q in:\n%s", line, got)
+		}
+	}
+}
+
+func TestCoverageModules(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/util.lox": {Data: []byte("fun twice(x) {\n  return x * 2;\n}\n")},
+	}
+	c := interpreter.NewCoverage()
+	interp := interpreter.New(
+		interpreter.WithOutput(&bytes.Buffer{}),
+		interpreter.WithLoader(interpreter.FSLoader{FS: fsys}),
+		interpreter.WithCoverage(c, "scripts/main.lox"),
+	)
+	if err := interp.Exec("import \"lib/util.lox\" as util;\nprint util.twice(2);\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := lcov(t, c)
+	if !strings.Contains(got, "SF:scripts/lib/util.lox\nBRF:0\nBRH:0\nDA:1,1\nDA:2,1\n") {
+		t.Errorf("unexpected module coverage:\n%s", got)
+	}
+}
+
+func TestCoverageHTML(t *testing.T) {
+	c := interpreter.NewCoverage()
+	cover(t, c, coverSrc)
+
+	var b bytes.Buffer
+	if err := c.WriteHTML(&b); err != nil {
+		t.Fatal(err)
+	}
+	html := b.String()
+	for _, s := range []string{
+		"lines 7/9, branches 4/6",
+		`<tr class="partial"><td class="num">2</td><td class="hits">1x</td><td class="branches">2/4</td>`,
+		`<tr class="missed"><td class="num">12</td><td class="hits">0x</td>`,
+		"&#34;never&#34;",
+	} {
+		if !strings.Contains(html, s) {
+			t.Errorf("missing %q in the HTML report", s)
+		}
+	}
+}
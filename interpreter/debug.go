@@ -0,0 +1,226 @@
+package interpreter
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// ErrAborted is returned by Exec when the debugger frontend aborts the
+// execution
+var ErrAborted = errors.New("execution aborted by the debugger")
+
+// Action tells the debugger how to resume after a stop
+type Action int
+
+const (
+	Continue Action = iota
+	// StepIn stops at the next statement, inside calls too
+	StepIn
+	// StepOver stops at the next statement of the current function
+	StepOver
+	// StepOut stops once the current function has returned
+	StepOut
+	Abort
+)
+
+type StopReason string
+
+const (
+	StopBreakpoint StopReason = "breakpoint"
+	StopStep       StopReason = "step"
+	StopPause      StopReason = "pause"
+)
+
+// Stopped describes where the execution is suspended, all the values
+// are captured when stopping so it can be kept after resuming
+type Stopped struct {
+	Reason StopReason
+	Line   int
+	// call stack, innermost frame first
+	Frames []Frame
+}
+
+type Frame struct {
+	Function string
+	// line of the statement being executed in this frame
+	Line int
+	// scopes visible from the frame, innermost first and the
+	// globals last
+	Scopes []Scope
+}
+
+type Scope struct {
+	Name string
+	Vars []Var
+}
+
+type Var struct {
+	Name  string
+	Value string
+}
+
+// Debugger suspends the execution on breakpoints and steps, and asks
+// its frontend how to resume. Breakpoints can be changed and a pause
+// requested from other goroutines while the program runs.
+type Debugger struct {
+	// onStop is called on the interpreter goroutine, which stays
+	// suspended until it returns
+	onStop func(*Stopped) Action
+
+	mu          sync.Mutex
+	breakpoints map[int]bool
+	mode        Action
+	// statement and stack depth of the last stop
+	last  parser.Stmt
+	depth int
+	// parts of the last statement stopped on skipped since then
+	skipped map[parser.Stmt]bool
+	paused  bool
+}
+
+func NewDebugger(onStop func(*Stopped) Action) *Debugger {
+	return &Debugger{
+		onStop:      onStop,
+		breakpoints: make(map[int]bool),
+	}
+}
+
+// WithDebugger executes every statement under the control of d
+func WithDebugger(d *Debugger) Option {
+	return func(i *Interpreter) {
+		i.debugger = d
+	}
+}
+
+// SetBreakpoints replaces the breakpoints by the given lines
+func (d *Debugger) SetBreakpoints(lines ...int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakpoints = make(map[int]bool)
+	for _, l := range lines {
+		d.breakpoints[l] = true
+	}
+}
+
+// Pause stops the execution before the next statement
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paused = true
+}
+
+// frame is a Lox call being executed, only tracked when debugging
+type frame struct {
+	function string
+	line     int
+	env      *Env
+}
+
+// before is called before executing s, it suspends the execution
+// when a breakpoint or a step is reached
+func (d *Debugger) before(i *Interpreter, s parser.Stmt) error {
+	top := i.frames[len(i.frames)-1]
+	top.line = s.Line()
+	top.env = i.env
+	depth := len(i.frames)
+
+	d.mu.Lock()
+	var reason StopReason
+	switch {
+	case d.paused:
+		reason = StopPause
+	case depth == d.depth && s.Line() == d.last.Line() && !d.skipped[s] && encloses(d.last, s):
+		// a part of the statement just stopped on, like the
+		// initializer of a for loop or the body of a one line if,
+		// even when there is a breakpoint on its line. Only once: the
+		// body of a one line loop stops again on the next iteration.
+		d.skipped[s] = true
+	case d.breakpoints[top.line]:
+		reason = StopBreakpoint
+	case d.mode == StepIn,
+		d.mode == StepOver && depth <= d.depth,
+		d.mode == StepOut && depth < d.depth:
+		reason = StopStep
+	}
+	d.mu.Unlock()
+	if reason == "" {
+		return nil
+	}
+
+	action := d.onStop(i.stopped(reason))
+	if action == Abort {
+		return ErrAborted
+	}
+
+	d.mu.Lock()
+	d.paused = false
+	d.mode = action
+	d.depth = depth
+	d.last = s
+	d.skipped = make(map[parser.Stmt]bool)
+	d.mu.Unlock()
+	return nil
+}
+
+// encloses tells whether inner is a statement nested in outer
+func encloses(outer, inner parser.Stmt) bool {
+	found := false
+	parser.Inspect(outer, func(n parser.Node) bool {
+		if n == inner && n != outer {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+func (i *Interpreter) stopped(reason StopReason) *Stopped {
+	st := &Stopped{Reason: reason, Line: i.frames[len(i.frames)-1].line}
+	for n := len(i.frames) - 1; n >= 0; n-- {
+		f := i.frames[n]
+		st.Frames = append(st.Frames, Frame{
+			Function: f.function,
+			Line:     f.line,
+			Scopes:   scopes(f.env),
+		})
+	}
+	return st
+}
+
+func scopes(env *Env) []Scope {
+	var res []Scope
+	for e := env; e != nil; e = e.parent {
+		var sc Scope
+		switch {
+		case e.parent == nil:
+			sc.Name = "Globals"
+		case len(res) == 0:
+			sc.Name = "Locals"
+		default:
+			sc.Name = "Enclosing"
+		}
+
+		for _, name := range e.Names() {
+			var val string
+			v, _ := e.lookup(name)
+			if isBuiltin(v) {
+				// builtins are the same everywhere, don't clutter
+				// the scopes with them
+				continue
+			}
+			switch v.kind {
+			case StringKind:
+				val = strconv.Quote(v.AsString())
+			default:
+				val = Stringify(v)
+			}
+			sc.Vars = append(sc.Vars, Var{Name: name, Value: val})
+		}
+		res = append(res, sc)
+	}
+	return res
+}
@@ -0,0 +1,159 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+const debugSrc = `var total = 0;
+fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+for (var i = 0; i < 2; i = i + 1) {
+  total = add(total, i);
+}
+print total;
+`
+
+// debug runs debugSrc, answering each stop with the next action and
+// recording where it stopped
+func debug(t *testing.T, breakpoints []int, pause bool, actions ...interpreter.Action) ([]string, []*interpreter.Stopped) {
+	var stops []string
+	var states []*interpreter.Stopped
+	d := interpreter.NewDebugger(func(st *interpreter.Stopped) interpreter.Action {
+		stops = append(stops, fmt.Sprintf("%s:%d", st.Frames[0].Function, st.Line))
+		states = append(states, st)
+		if len(actions) == 0 {
+			return interpreter.Continue
+		}
+		a := actions[0]
+		actions = actions[1:]
+		return a
+	})
+	d.SetBreakpoints(breakpoints...)
+	if pause {
+		d.Pause()
+	}
+
+	var out bytes.Buffer
+	interp := interpreter.New(interpreter.WithOutput(&out), interpreter.WithDebugger(d))
+	if err := interp.Exec(debugSrc); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1\n" {
+		t.Errorf("debugging changed the output: %q", out.String())
+	}
+	return stops, states
+}
+
+func TestDebuggerStepping(t *testing.T) {
+	tests := []struct {
+		name        string
+		breakpoints []int
+		pause       bool
+		actions     []interpreter.Action
+		expected    []string
+	}{
+		{
+			name:        "breakpoints",
+			breakpoints: []int{3, 9},
+			expected:    []string{"<fn add>:3", "<fn add>:3", "<script>:9"},
+		},
+		{
+			name:     "step in",
+			pause:    true,
+			actions:  []interpreter.Action{interpreter.StepIn, interpreter.StepIn, interpreter.StepIn, interpreter.StepIn, interpreter.StepIn},
+			expected: []string{"<script>:1", "<script>:2", "<script>:6", "<script>:7", "<fn add>:3", "<fn add>:4"},
+		},
+		{
+			name:     "step over",
+			pause:    true,
+			actions:  []interpreter.Action{interpreter.StepOver, interpreter.StepOver, interpreter.StepOver, interpreter.StepOver, interpreter.StepOver},
+			expected: []string{"<script>:1", "<script>:2", "<script>:6", "<script>:7", "<script>:7", "<script>:9"},
+		},
+		{
+			name:        "step out",
+			breakpoints: []int{4},
+			actions:     []interpreter.Action{interpreter.StepOut, interpreter.Continue, interpreter.StepOut},
+			expected:    []string{"<fn add>:4", "<script>:7", "<fn add>:4", "<script>:9"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stops, _ := debug(t, tt.breakpoints, tt.pause, tt.actions...)
+			if !reflect.DeepEqual(stops, tt.expected) {
+				t.Errorf("unexpected stops %v, expected %v", stops, tt.expected)
+			}
+		})
+	}
+}
+
+// a breakpoint on a compound statement written on one line stops once
+// each time it runs, not again before the statements it contains. The
+// body of a one line loop stops on each iteration after the first.
+func TestDebuggerOneLineBreakpoint(t *testing.T) {
+	var stops []int
+	d := interpreter.NewDebugger(func(st *interpreter.Stopped) interpreter.Action {
+		stops = append(stops, st.Line)
+		return interpreter.Continue
+	})
+	d.SetBreakpoints(2, 3, 5)
+
+	var out bytes.Buffer
+	interp := interpreter.New(interpreter.WithOutput(&out), interpreter.WithDebugger(d))
+	err := interp.Exec(`var x = true;
+if (x) print 1;
+for (var i = 0; i < 2; i = i + 1) print i;
+var j = 0;
+while (j < 3) j = j + 1;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1\n0\n1\n" {
+		t.Errorf("debugging changed the output: %q", out.String())
+	}
+	if expected := []int{2, 3, 3, 5, 5, 5}; !reflect.DeepEqual(stops, expected) {
+		t.Errorf("unexpected stops %v, expected %v", stops, expected)
+	}
+}
+
+func TestDebuggerInspection(t *testing.T) {
+	_, states := debug(t, []int{4}, false)
+	st := states[1]
+
+	if len(st.Frames) != 2 || st.Frames[1].Function != "<script>" || st.Frames[1].Line != 7 {
+		t.Fatalf("unexpected stack: %+v", st.Frames)
+	}
+
+	expected := []interpreter.Scope{
+		{Name: "Locals", Vars: []interpreter.Var{{Name: "sum", Value: "1"}}},
+		{Name: "Enclosing", Vars: []interpreter.Var{{Name: "a", Value: "0"}, {Name: "b", Value: "1"}}},
+		{Name: "Globals", Vars: []interpreter.Var{{Name: "add", Value: "<fn add>"}, {Name: "total", Value: "0"}}},
+	}
+	if !reflect.DeepEqual(st.Frames[0].Scopes, expected) {
+		t.Errorf("unexpected scopes:\n%+v\nexpected:\n%+v", st.Frames[0].Scopes, expected)
+	}
+}
+
+func TestDebuggerAbort(t *testing.T) {
+	d := interpreter.NewDebugger(func(*interpreter.Stopped) interpreter.Action {
+		return interpreter.Abort
+	})
+	d.Pause()
+
+	var out bytes.Buffer
+	interp := interpreter.New(interpreter.WithOutput(&out), interpreter.WithDebugger(d))
+	if err := interp.Exec(debugSrc); err != interpreter.ErrAborted {
+		t.Errorf("expected ErrAborted, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("nothing should have run, got %q", out.String())
+	}
+}
@@ -0,0 +1,180 @@
+package interpreter
+
+import (
+	"sort"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// Env holds the variables of a scope. Globals are stored by name,
+// locals in slots computed by parser.Resolve: the interpreter creates
+// a local environment for each scope the resolver created, so a slot's
+// depth is the number of parents to go through.
+type Env struct {
+	parent *Env
+	root   *Env
+
+	// globals only
+	values map[string]Value
+	// read-only globals of the interpreter this one was forked from,
+	// looked up for names not in values
+	base *Env
+	// locals only, indexed like scope.Names
+	slots []Value
+	scope *parser.Scope
+
+	// only set on the root environment of a module
+	importer *moduleImporter
+	// inherited from the parent
+	hooks Hooks
+}
+
+// NewEnv creates an environment storing its variables by name
+func NewEnv(parent *Env) *Env {
+	env := &Env{
+		parent: parent,
+		values: make(map[string]Value),
+	}
+	env.inherit(parent)
+	return env
+}
+
+// newLocalEnv creates the environment of a resolved scope
+func newLocalEnv(parent *Env, scope *parser.Scope) *Env {
+	env := &Env{
+		parent: parent,
+		slots:  make([]Value, len(scope.Names)),
+		scope:  scope,
+	}
+	env.inherit(parent)
+	return env
+}
+
+func (e *Env) inherit(parent *Env) {
+	if parent == nil {
+		e.root = e
+		return
+	}
+	e.root = parent.root
+	e.hooks = parent.hooks
+}
+
+func (e *Env) Root() *Env {
+	return e.root
+}
+
+// Names returns the sorted names defined directly in this environment
+func (e *Env) Names() []string {
+	var names []string
+	if e.scope != nil {
+		names = append(names, e.scope.Names...)
+	}
+	seen := make(map[string]bool)
+	for g := e; g != nil; g = g.base {
+		for name := range g.values {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup finds name directly in this environment, or in the globals
+// it is based on
+func (e *Env) lookup(name string) (Value, bool) {
+	if e.scope != nil {
+		for idx, n := range e.scope.Names {
+			if n == name {
+				return e.slots[idx], true
+			}
+		}
+		return Value{}, false
+	}
+	for g := e; g != nil; g = g.base {
+		if v, ok := g.values[name]; ok {
+			return v, true
+		}
+	}
+	return Value{}, false
+}
+
+// Define creates or replaces a variable. In a local environment only
+// the names of its scope can be defined, defining globals never fails.
+func (e *Env) Define(name string, value Value) error {
+	if e.scope != nil {
+		for idx, n := range e.scope.Names {
+			if n == name {
+				e.slots[idx] = value
+				return nil
+			}
+		}
+		return &RuntimeError{Msg: name + " is not declared in this scope"}
+	}
+	e.values[name] = value
+	return nil
+}
+
+func (e *Env) Get(name string) (Value, error) {
+	if v, ok := e.lookup(name); ok {
+		return v, nil
+	}
+	if e.parent != nil {
+		return e.parent.Get(name)
+	}
+	return Value{}, &RuntimeError{Msg: "undefined variable " + name}
+}
+
+func (e *Env) Set(name string, value Value) error {
+	if e.scope != nil {
+		for idx, n := range e.scope.Names {
+			if n == name {
+				e.setSlot(idx, value)
+				return nil
+			}
+		}
+	} else if _, ok := e.lookup(name); ok {
+		// a variable of the base is copied on write
+		e.values[name] = value
+		if e.hooks != nil {
+			e.hooks.Assign(name, value)
+		}
+		return nil
+	}
+	if e.parent != nil {
+		return e.parent.Set(name, value)
+	}
+	return &RuntimeError{Msg: "undefined variable " + name}
+}
+
+// at returns the local environment holding slot
+func (e *Env) at(slot *parser.Slot) *Env {
+	env := e
+	for d := 0; d < slot.Depth; d++ {
+		env = env.parent
+	}
+	return env
+}
+
+func (e *Env) setSlot(idx int, value Value) {
+	e.slots[idx] = value
+	if e.hooks != nil {
+		e.hooks.Assign(e.scope.Names[idx], value)
+	}
+}
+
+// freeze moves the variables of the globals env e to a read-only base,
+// shared with the environments forked from e: writes to either of them
+// then stay private
+func (e *Env) freeze() *Env {
+	if len(e.values) == 0 && e.base != nil {
+		return e.base
+	}
+	base := &Env{values: e.values, base: e.base}
+	base.root = base
+	e.values = make(map[string]Value)
+	e.base = base
+	return base
+}
@@ -0,0 +1,26 @@
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+func TestEnvDefine(t *testing.T) {
+	globals := NewEnv(nil)
+	if err := globals.Define("anything", Number(1)); err != nil {
+		t.Errorf("defining a global: %v", err)
+	}
+
+	local := newLocalEnv(globals, &parser.Scope{Names: []string{"a"}})
+	if err := local.Define("a", Number(2)); err != nil {
+		t.Errorf("defining a declared local: %v", err)
+	}
+	if v, err := local.Get("a"); err != nil || Stringify(v) != "2" {
+		t.Errorf("got %v, %v", v, err)
+	}
+	err := local.Define("b", Number(3))
+	if err == nil || err.Error() != "runtime error: b is not declared in this scope" {
+		t.Errorf("got error %v", err)
+	}
+}
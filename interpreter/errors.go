@@ -0,0 +1,101 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+type RuntimeError struct {
+	Msg  string
+	Line int
+	// call stack when the error happened, innermost call first
+	Trace []string
+}
+
+func (e *RuntimeError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("runtime error: %s, line %d", e.Msg, e.Line)
+	}
+	return "runtime error: " + e.Msg
+}
+
+// locate attaches the line of tok to err if it is a RuntimeError or a
+// ModuleError that doesn't know where it happened yet
+func locate(err error, tok *parser.Token) error {
+	switch err := err.(type) {
+	case *RuntimeError:
+		if err.Line == 0 {
+			err.Line = tok.Line
+		}
+	case *ModuleError:
+		if err.Line == 0 {
+			err.Line = tok.Line
+		}
+	}
+	return err
+}
+
+// addTrace appends frames to the trace of err, if it has one
+func addTrace(err error, frames ...string) {
+	switch err := err.(type) {
+	case *RuntimeError:
+		err.Trace = append(err.Trace, frames...)
+	case *Thrown:
+		err.Trace = append(err.Trace, frames...)
+	case *ModuleError:
+		addTrace(err.Err, frames...)
+	}
+}
+
+// Thrown is the error bubbling up from a throw statement, or from a
+// runtime error, until a try statement catches it
+type Thrown struct {
+	Value Value
+	Line  int
+	Trace []string
+}
+
+func (t *Thrown) Error() string {
+	return fmt.Sprintf("uncaught exception: %v, line %d", t.Value, t.Line)
+}
+
+// ErrorValue is the value received by a catch clause for runtime errors
+// raised by the interpreter itself
+type ErrorValue struct {
+	Message string
+	Line    int
+	Trace   []string
+}
+
+func (e *ErrorValue) Get(name string) (Value, error) {
+	switch name {
+	case "message":
+		return String(e.Message), nil
+	case "line":
+		return Number(float64(e.Line)), nil
+	case "trace":
+		return String(strings.Join(e.Trace, "\n")), nil
+	}
+	return Value{}, &RuntimeError{Msg: "error has no property " + name}
+}
+
+func (e *ErrorValue) String() string {
+	return fmt.Sprintf("<error %s, line %d>", e.Message, e.Line)
+}
+
+// caughtValue returns the Lox value a catch clause receives for err,
+// the one of the original error for a module that failed. Other errors,
+// like an aborted debugging session, can't be caught
+func caughtValue(err error) (Value, bool) {
+	switch err := err.(type) {
+	case *Thrown:
+		return err.Value, true
+	case *RuntimeError:
+		return Object(&ErrorValue{Message: err.Msg, Line: err.Line, Trace: err.Trace}), true
+	case *ModuleError:
+		return caughtValue(err.Err)
+	}
+	return Value{}, false
+}
@@ -0,0 +1,576 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// completion tells how a statement finished: normally, or with a
+// return unwinding to the enclosing function. It's kept apart from
+// errors, which are only used for runtime errors and thrown values.
+type completion struct {
+	kind completionKind
+	// value of a return statement
+	value Value
+}
+
+type completionKind uint8
+
+const (
+	normal completionKind = iota
+	returned
+	// the function returns the result of Interpreter.tailCall, which
+	// its caller makes once the function's Go frames are gone
+	tailCalled
+	// break and continue will unwind loops the same way
+)
+
+// pendingCall is a call made in tail position, waiting for the
+// function to return
+type pendingCall struct {
+	fn    *LoxFunction
+	args  []Value
+	paren *parser.Token
+}
+
+// execute runs stmts in env, the current environment is restored
+// once they are done
+func (i *Interpreter) execute(stmts []parser.Stmt, env *Env) (completion, error) {
+	prev := i.env
+	i.env = env
+	defer func() { i.env = prev }()
+
+	for _, s := range stmts {
+		if c, err := i.exec(s); err != nil || c.kind != normal {
+			return c, err
+		}
+	}
+	return completion{}, nil
+}
+
+// exec runs a single statement, after letting the hooks, the coverage
+// and the profiler record it and the debugger stop before it. Blocks
+// are not seen by any of them, their statements are.
+func (i *Interpreter) exec(s parser.Stmt) (completion, error) {
+	if i.debugger != nil || i.profiler != nil || i.coverage != nil || i.hooks != nil {
+		if _, ok := s.(*parser.Block); !ok {
+			if i.hooks != nil {
+				i.hooks.Statement(s)
+			}
+			if i.coverage != nil {
+				i.coverage.statement(s)
+			}
+			if i.profiler != nil {
+				i.profiler.statement(s)
+			}
+			if i.debugger != nil {
+				if err := i.debugger.before(i, s); err != nil {
+					return completion{}, err
+				}
+			}
+		}
+	}
+
+	c, err := i.dispatch(s)
+	if err != nil && i.hooks != nil {
+		i.raised(err)
+	}
+	return c, err
+}
+
+// dispatch runs s through the statement visitor
+func (i *Interpreter) dispatch(s parser.Stmt) (completion, error) {
+	return parser.VisitStmt[completion](s, evaluator{i})
+}
+
+// evaluate computes e through the expression visitor
+func (i *Interpreter) evaluate(e parser.Expr) (Value, error) {
+	return parser.VisitExpr[Value](e, evaluator{i})
+}
+
+// evaluator implements the parser visitors for the interpreter, the
+// statements end with a completion and expressions give a Value
+type evaluator struct {
+	*Interpreter
+}
+
+// --- statements
+
+func (i evaluator) VisitPrintStmt(e *parser.PrintStmt) (completion, error) {
+	v, err := i.evaluate(e.Value)
+	if err != nil {
+		return completion{}, err
+	}
+	fmt.Fprintln(i.out, Stringify(v))
+	return completion{}, nil
+}
+
+func (i evaluator) VisitReturnStmt(e *parser.ReturnStmt) (completion, error) {
+	if e.Value == nil {
+		return completion{kind: returned}, nil
+	}
+
+	if call, ok := e.Value.(*parser.Call); ok && e.Tail {
+		return i.execTailCall(call)
+	}
+
+	v, err := i.evaluate(e.Value)
+	if err != nil {
+		return completion{}, err
+	}
+	return completion{kind: returned, value: v}, nil
+}
+
+// execTailCall returns a call to a Lox function for the enclosing
+// function to make once it returned, so recursion doesn't grow the Go
+// stack. Calls are always made in place while the debugger, the
+// profiler or the hooks follow the call stack.
+func (i *Interpreter) execTailCall(e *parser.Call) (completion, error) {
+	callable, args, err := i.callArgs(e)
+	if err != nil {
+		return completion{}, err
+	}
+
+	fn, ok := callable.(*LoxFunction)
+	if !ok || i.debugger != nil || i.profiler != nil || i.hooks != nil {
+		v, err := i.call(callable, args, e.Paren)
+		return completion{kind: returned, value: v}, err
+	}
+	i.tailCall = pendingCall{fn: fn, args: args, paren: e.Paren}
+	return completion{kind: tailCalled}, nil
+}
+
+func (i evaluator) VisitThrowStmt(e *parser.ThrowStmt) (completion, error) {
+	v, err := i.evaluate(e.Value)
+	if err != nil {
+		return completion{}, err
+	}
+	return completion{}, &Thrown{Value: v, Line: e.Keyword.Line}
+}
+
+func (i evaluator) VisitTryStmt(e *parser.TryStmt) (completion, error) {
+	c, err := i.exec(e.Body)
+
+	if err != nil && e.CatchBody != nil {
+		if val, ok := caughtValue(err); ok {
+			scope := newLocalEnv(i.env, e.CatchScope)
+			scope.slots[0] = val
+			c, err = i.execute([]parser.Stmt{e.CatchBody}, scope)
+		}
+	}
+
+	// finally always runs, a return or throw inside it replaces
+	// whatever was unwinding through the try statement
+	if e.FinallyBody != nil {
+		if fc, ferr := i.exec(e.FinallyBody); ferr != nil || fc.kind != normal {
+			return fc, ferr
+		}
+	}
+	return c, err
+}
+
+func (i evaluator) VisitExprStmt(e *parser.ExprStmt) (completion, error) {
+	_, err := i.evaluate(e.Value)
+	return completion{}, err
+}
+
+func (i evaluator) VisitFunStmt(e *parser.FunStmt) (completion, error) {
+	i.define(e.Local, e.Name.Lexeme, Object(&LoxFunction{
+		Declaration: e,
+		Globals:     i.env.Root(),
+	}))
+	return completion{}, nil
+}
+
+func (i evaluator) VisitImportStmt(e *parser.ImportStmt) (completion, error) {
+	importer := i.env.Root().importer
+	if importer == nil {
+		return completion{}, &RuntimeError{Msg: "import is not supported", Line: e.Keyword.Line}
+	}
+
+	mod, err := importer.Import(e.Path.Literal.(string))
+	if err != nil {
+		return completion{}, locate(err, e.Keyword)
+	}
+	i.define(e.Local, e.Name.Lexeme, Object(mod))
+	return completion{}, nil
+}
+
+func (i evaluator) VisitVarDecl(e *parser.VarDecl) (completion, error) {
+	var init Value
+	if e.Init != nil {
+		v, err := i.evaluate(e.Init)
+		if err != nil {
+			return completion{}, err
+		}
+		init = v
+	}
+	i.define(e.Local, e.Name.Lexeme, init)
+	return completion{}, nil
+}
+
+// define declares name in the current scope, locals go in their slot
+func (i *Interpreter) define(slot *parser.Slot, name string, v Value) {
+	if slot != nil {
+		i.env.slots[slot.Index] = v
+		return
+	}
+	i.env.Define(name, v)
+}
+
+func (i evaluator) VisitBlock(e *parser.Block) (completion, error) {
+	if e.Scope == nil {
+		return i.execute(e.Statements, i.env)
+	}
+	return i.execute(e.Statements, newLocalEnv(i.env, e.Scope))
+}
+
+func (i evaluator) VisitIfStmt(e *parser.IfStmt) (completion, error) {
+	val, err := i.evaluate(e.Expr)
+	if err != nil {
+		return completion{}, err
+	}
+
+	if isTruthy(val) {
+		if i.coverage != nil {
+			i.coverage.branch(e, branchTaken)
+		}
+		return i.exec(e.ThenBrch)
+	} else {
+		if i.coverage != nil {
+			i.coverage.branch(e, branchNotTaken)
+		}
+		if e.ElseBrch != nil {
+			return i.exec(e.ElseBrch)
+		}
+	}
+	return completion{}, nil
+}
+
+func (i evaluator) VisitWhileStmt(e *parser.WhileStmt) (completion, error) {
+	for {
+		cond, err := i.evaluate(e.Expr)
+		if err != nil {
+			return completion{}, err
+		}
+		if !isTruthy(cond) {
+			if i.coverage != nil {
+				i.coverage.branch(e, branchNotTaken)
+			}
+			return completion{}, nil
+		}
+
+		if i.coverage != nil {
+			i.coverage.branch(e, branchTaken)
+		}
+		if c, err := i.exec(e.Body); err != nil || c.kind != normal {
+			return c, err
+		}
+	}
+}
+
+func (i evaluator) VisitForStmt(e *parser.ForStmt) (completion, error) {
+	if e.Scope != nil {
+		prev := i.env
+		i.env = newLocalEnv(i.env, e.Scope)
+		defer func() { i.env = prev }()
+	}
+
+	if e.Init != nil {
+		if _, err := i.exec(e.Init); err != nil {
+			return completion{}, err
+		}
+	}
+	for {
+		if e.Cond != nil {
+			cond, err := i.evaluate(e.Cond)
+			if err != nil {
+				return completion{}, err
+			}
+			if !isTruthy(cond) {
+				if i.coverage != nil {
+					i.coverage.branch(e, branchNotTaken)
+				}
+				return completion{}, nil
+			}
+		}
+
+		if i.coverage != nil {
+			i.coverage.branch(e, branchTaken)
+		}
+		if c, err := i.exec(e.Body); err != nil || c.kind != normal {
+			return c, err
+		}
+
+		if e.Incr != nil {
+			if _, err := i.evaluate(e.Incr); err != nil {
+				return completion{}, err
+			}
+		}
+	}
+}
+
+// --- expressions
+
+func (i evaluator) VisitBinaryExpr(e *parser.BinaryExpr) (Value, error) {
+	l, err := i.evaluate(e.Left)
+	if err != nil {
+		return Value{}, err
+	}
+	r, err := i.evaluate(e.Right)
+	if err != nil {
+		return Value{}, err
+	}
+
+	numbers := l.kind == NumberKind && r.kind == NumberKind
+	strs := l.kind == StringKind && r.kind == StringKind
+
+	switch e.Op.Typ {
+	case parser.PLUS:
+		if numbers {
+			return Number(l.num + r.num), nil
+		}
+		if strs {
+			return String(l.AsString() + r.AsString()), nil
+		}
+	case parser.MINUS:
+		if numbers {
+			return Number(l.num - r.num), nil
+		}
+	case parser.STAR:
+		if numbers {
+			return Number(l.num * r.num), nil
+		}
+	case parser.SLASH:
+		if numbers {
+			return Number(l.num / r.num), nil
+		}
+	case parser.EQUAL_EQUAL:
+		eq, err := isEqual(l, r)
+		return Bool(eq), locate(err, e.Op)
+	case parser.BANG_EQUAL:
+		eq, err := isEqual(l, r)
+		return Bool(!eq), locate(err, e.Op)
+	case parser.LESS_EQUAL:
+		if numbers {
+			return Bool(l.num <= r.num), nil
+		}
+	case parser.LESS:
+		if numbers {
+			return Bool(l.num < r.num), nil
+		}
+		if strs {
+			return Bool(l.AsString() < r.AsString()), nil
+		}
+	case parser.GREATER_EQUAL:
+		if numbers {
+			return Bool(l.num >= r.num), nil
+		}
+	case parser.GREATER:
+		if numbers {
+			return Bool(l.num > r.num), nil
+		}
+		if strs {
+			return Bool(l.AsString() > r.AsString()), nil
+		}
+	}
+
+	return Value{}, &RuntimeError{
+		Msg:  fmt.Sprintf("unimplemented operation %s %v %s", typeName(l), e.Op.Lexeme, typeName(r)),
+		Line: e.Op.Line,
+	}
+}
+
+func (i evaluator) VisitUnaryExpr(e *parser.UnaryExpr) (Value, error) {
+	r, err := i.evaluate(e.Right)
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch e.Op.Typ {
+	case parser.MINUS:
+		if r.kind == NumberKind {
+			return Number(-r.num), nil
+		}
+		return Value{}, &RuntimeError{
+			Msg:  "operand of - must be a number, got " + typeName(r),
+			Line: e.Op.Line,
+		}
+	}
+	return Value{}, &RuntimeError{Msg: "unimplemented", Line: e.Op.Line}
+}
+
+func (i evaluator) VisitGroupingExpr(e *parser.GroupingExpr) (Value, error) {
+	return i.evaluate(e.Expr)
+}
+
+func (i evaluator) VisitLiteralExpr(e *parser.LiteralExpr) (Value, error) {
+	switch e.Value.Typ {
+	case parser.NIL:
+		return Value{}, nil
+	case parser.FALSE:
+		return Bool(false), nil
+	case parser.TRUE:
+		return Bool(true), nil
+	case parser.STRING:
+		// reuse the string already boxed in the token
+		return Value{kind: StringKind, obj: e.Value.Literal}, nil
+	}
+	return ValueOf(e.Value.Literal), nil
+}
+
+func (i evaluator) VisitVariable(e *parser.Variable) (Value, error) {
+	if e.Local != nil {
+		return i.env.at(e.Local).slots[e.Local.Index], nil
+	}
+	v, err := i.env.Root().Get(e.Name.Lexeme)
+	return v, locate(err, e.Name)
+}
+
+func (i evaluator) VisitAssign(e *parser.Assign) (Value, error) {
+	v, err := i.evaluate(e.Value)
+	if err != nil {
+		return Value{}, err
+	}
+	if e.Local != nil {
+		i.env.at(e.Local).setSlot(e.Local.Index, v)
+		return v, nil
+	}
+	return v, locate(i.env.Root().Set(e.Name.Lexeme, v), e.Name)
+}
+
+func (i evaluator) VisitLogical(e *parser.Logical) (Value, error) {
+	l, err := i.evaluate(e.Left)
+	if err != nil {
+		return Value{}, err
+	}
+
+	// 'or' stops on a truthy left operand, 'and' on a falsy one
+	shortCircuit := isTruthy(l)
+	if e.Operator.Typ != parser.OR {
+		shortCircuit = !shortCircuit
+	}
+	if shortCircuit {
+		if i.coverage != nil {
+			i.coverage.branch(e, branchNotTaken)
+		}
+		return l, nil
+	}
+
+	if i.coverage != nil {
+		i.coverage.branch(e, branchTaken)
+	}
+	return i.evaluate(e.Right)
+}
+
+func (i evaluator) VisitCall(e *parser.Call) (Value, error) {
+	callable, args, err := i.callArgs(e)
+	if err != nil {
+		return Value{}, err
+	}
+	return i.call(callable, args, e.Paren)
+}
+
+// callArgs evaluates the callee and the arguments of e, and checks
+// they can be called
+func (i *Interpreter) callArgs(e *parser.Call) (Callable, []Value, error) {
+	callee, err := i.evaluate(e.Callee)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	args := make([]Value, 0, len(e.Args))
+	for _, a := range e.Args {
+		arg, err := i.evaluate(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, arg)
+	}
+
+	callable, ok := callee.obj.(Callable)
+	if !ok {
+		return nil, nil, &RuntimeError{
+			Msg:  "can only call functions and classes",
+			Line: e.Paren.Line,
+		}
+	}
+
+	if callable.Arity() != len(args) {
+		return nil, nil, &RuntimeError{
+			Msg:  fmt.Sprintf("expected %d arguments but got %d", callable.Arity(), len(args)),
+			Line: e.Paren.Line,
+		}
+	}
+	return callable, args, nil
+}
+
+// call calls callable from the call site paren
+func (i *Interpreter) call(callable Callable, args []Value, paren *parser.Token) (Value, error) {
+	if i.debugger != nil {
+		i.frames = append(i.frames, &frame{function: fmt.Sprint(callable), env: i.env})
+		defer func() { i.frames = i.frames[:len(i.frames)-1] }()
+	}
+	if i.profiler != nil {
+		i.profiler.enter(callableName(callable))
+		defer i.profiler.exit()
+	}
+	if i.hooks != nil {
+		i.hooks.CallEnter(callable, args, paren.Line)
+	}
+
+	// natives don't know where they are called from, errors
+	// they return are reported at the call site
+	v, err := callable.Call(CallContext{interp: i, paren: paren}, args)
+	if err != nil {
+		err = locate(err, paren)
+		addTrace(err, fmt.Sprintf("%v, line %d", callable, paren.Line))
+	}
+
+	if i.hooks != nil {
+		i.hooks.CallExit(callable, v, err)
+	}
+	return v, err
+}
+
+func (i evaluator) VisitGetExpr(e *parser.GetExpr) (Value, error) {
+	obj, err := i.evaluate(e.Object)
+	if err != nil {
+		return Value{}, err
+	}
+
+	if mod, ok := obj.obj.(*Module); ok {
+		v, err := mod.get(i.moduleGlobals(mod), e.Name.Lexeme)
+		return v, locate(err, e.Name)
+	}
+	inst, ok := obj.obj.(Instance)
+	if !ok {
+		return Value{}, &RuntimeError{
+			Msg:  "only modules, errors and Go values have properties, got " + typeName(obj),
+			Line: e.Name.Line,
+		}
+	}
+	v, err := inst.Get(e.Name.Lexeme)
+	return v, locate(err, e.Name)
+}
+
+func (i evaluator) VisitSetExpr(e *parser.SetExpr) (Value, error) {
+	obj, err := i.evaluate(e.Object)
+	if err != nil {
+		return Value{}, err
+	}
+
+	inst, ok := obj.obj.(Settable)
+	if !ok {
+		return Value{}, &RuntimeError{
+			Msg:  "only Go values have settable properties, got " + typeName(obj),
+			Line: e.Name.Line,
+		}
+	}
+	v, err := i.evaluate(e.Value)
+	if err != nil {
+		return Value{}, err
+	}
+	return v, locate(inst.Set(e.Name.Lexeme, v), e.Name)
+}
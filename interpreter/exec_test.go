@@ -0,0 +1,27 @@
+package interpreter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExecOutput(t *testing.T) {
+	var out bytes.Buffer
+	i := New(WithOutput(&out))
+	err := i.Exec(`
+		print nil;
+		print 1.5;
+		print 1000000 * 1000000 * 1000000 * 1000;
+		print "text";
+		print split("a,b", ",");
+		print 1 + nil;
+		print "never";
+	`)
+	if err == nil || !strings.Contains(err.Error(), "unimplemented operation") {
+		t.Errorf("got error %v", err)
+	}
+	if want := "nil\n1.5\n1000000000000000000000\ntext\n[a, b]\n"; out.String() != want {
+		t.Errorf("got output %q, expected %q", out.String(), want)
+	}
+}
@@ -0,0 +1,29 @@
+package interpreter
+
+import (
+	"time"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// SetClock replaces the clock of p, every call to now advancing by tick
+func SetClock(p *Profiler, tick time.Duration) {
+	var t time.Time
+	p.now = func() time.Time {
+		t = t.Add(tick)
+		return t
+	}
+}
+
+// Evaluator parses the expression src once and returns a function
+// evaluating it in the globals of i
+func Evaluator(i *Interpreter, src string) (func() (Value, error), error) {
+	stmts, err := i.parse(src + ";")
+	if err != nil {
+		return nil, err
+	}
+	expr := stmts[0].(*parser.ExprStmt).Value
+	return func() (Value, error) {
+		return i.evaluate(expr)
+	}, nil
+}
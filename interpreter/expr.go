@@ -0,0 +1,91 @@
+package interpreter
+
+import (
+	"sort"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// EvalOption configures a single call to EvalExpr
+type EvalOption func(*evalConfig)
+
+type evalConfig struct {
+	noSideEffects bool
+}
+
+// WithoutSideEffects makes EvalExpr reject the expression if it calls a
+// function or assigns a variable, before evaluating it
+func WithoutSideEffects() EvalOption {
+	return func(c *evalConfig) {
+		c.noSideEffects = true
+	}
+}
+
+// EvalExpr evaluates src, a single expression such as
+// `age >= 18 and country == "FR"`, and returns its result converted
+// with Value.Interface. The globals are visible to the expression
+// along with vars, converted with ValueOf; vars are not defined in the
+// globals, they only live for this evaluation. opts only apply to this
+// evaluation too.
+func (i *Interpreter) EvalExpr(src string, vars map[string]any, opts ...EvalOption) (any, error) {
+	var cfg evalConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	tokens, err := parser.NewScanner(src).Scan()
+	if err != nil {
+		return nil, err
+	}
+	expr, err := parser.New(tokens).ParseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.noSideEffects {
+		if err := checkNoSideEffects(expr); err != nil {
+			return nil, err
+		}
+	}
+
+	scope := &parser.Scope{}
+	for name := range vars {
+		scope.Names = append(scope.Names, name)
+	}
+	sort.Strings(scope.Names)
+	parser.ResolveExpr(expr, scope)
+
+	env := newLocalEnv(i.globals, scope)
+	for idx, name := range scope.Names {
+		env.slots[idx] = ValueOf(vars[name])
+	}
+	prev := i.env
+	i.env = env
+	defer func() { i.env = prev }()
+
+	v, err := i.evaluate(expr)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// checkNoSideEffects returns a syntax error on the first call or
+// assignment of e
+func checkNoSideEffects(e parser.Expr) error {
+	var err error
+	parser.Inspect(e, func(n parser.Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *parser.Call:
+			err = &parser.SyntaxError{Msg: "calls are not allowed", Token: n.Paren}
+		case *parser.Assign:
+			err = &parser.SyntaxError{Msg: "assignments are not allowed", Token: n.Name}
+		case *parser.SetExpr:
+			err = &parser.SyntaxError{Msg: "assignments are not allowed", Token: n.Name}
+		}
+		return err == nil
+	})
+	return err
+}
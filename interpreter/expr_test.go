@@ -0,0 +1,77 @@
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+func TestEvalExpr(t *testing.T) {
+	interp := interpreter.New()
+	if err := interp.Exec(`var adult = 18; fun double(n) { return n * 2; }`); err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]any{"age": 21, "country": "FR", "score": 2.5, "vip": nil}
+
+	tests := []struct {
+		src      string
+		expected any
+		err      string
+	}{
+		{src: `age >= 18 and country == "FR"`, expected: true},
+		{src: `age >= adult and country == "DE"`, expected: false},
+		{src: `(score + 1) * 2`, expected: 7.0},
+		{src: `vip or "none"`, expected: "none"},
+		{src: `double(age)`, expected: 42.0},
+		{src: `age = 3`, expected: 3.0},
+		{src: `age + 1;`, err: "syntax error: unexpected token after expression, line 1: ';'"},
+		{src: `age age`, err: "syntax error: unexpected token after expression, line 1: 'age'"},
+		{src: `unknown > 1`, err: "runtime error: undefined variable unknown, line 1"},
+		{src: `country > 1`, err: "runtime error: unimplemented operation string > number, line 1"},
+	}
+	for _, tt := range tests {
+		got, err := interp.EvalExpr(tt.src, vars)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: got error %v, expected %s", tt.src, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+		} else if got != tt.expected {
+			t.Errorf("%s: got %#v, expected %#v", tt.src, got, tt.expected)
+		}
+	}
+
+	// variables don't outlive the evaluation
+	if _, err := interp.Globals().Get("age"); err == nil {
+		t.Error("age was defined in the globals")
+	}
+}
+
+func TestEvalExprWithoutSideEffects(t *testing.T) {
+	interp := interpreter.New()
+	vars := map[string]any{"age": 21}
+
+	for src, expected := range map[string]string{
+		`age * 2 > 40`:         "",
+		`len("abc") > 2`:       "syntax error: calls are not allowed, line 1: ')'",
+		`age > 1 or (age = 3)`: "syntax error: assignments are not allowed, line 1: 'age'",
+		`age.x = 3`:            "syntax error: assignments are not allowed, line 1: 'x'",
+	} {
+		_, err := interp.EvalExpr(src, vars, interpreter.WithoutSideEffects())
+		if expected == "" {
+			if err != nil {
+				t.Errorf("%s: %v", src, err)
+			}
+		} else if err == nil || err.Error() != expected {
+			t.Errorf("%s: got error %v, expected %s", src, err, expected)
+		}
+	}
+
+	// the option only applies to the call it is given to
+	if got, err := interp.EvalExpr(`len("abc")`, nil); err != nil || got != 3.0 {
+		t.Errorf("got %v, %v after a call without side effects", got, err)
+	}
+}
@@ -0,0 +1,98 @@
+package interpreter
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Fork returns a new interpreter seeing the globals and the modules of
+// i as they are now, without running anything again. Globals are copied
+// on write: the fork can read and call everything i defined, but what
+// it defines or assigns stays private, and so does what i changes
+// afterwards.
+//
+// Forks share nothing mutable, each can run in its own goroutine. i
+// must not be running meanwhile, but it can be forked from several
+// goroutines at once. Forks keep the output, I/O, loader and optimizer
+// of i and opts are applied on top; the
+// debugger, the profiler, the coverage and the hooks are not inherited
+// as they follow a single interpreter.
+func (i *Interpreter) Fork(opts ...Option) *Interpreter {
+	i.forkMu.Lock()
+	defer i.forkMu.Unlock()
+
+	f := &Interpreter{
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		io:       i.io,
+		out:      i.out,
+		optimize: i.optimize,
+		loader:   i.loader,
+		modules:  make(map[string]*Module),
+		forked:   make(map[*Env]*Env),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	f.globals = f.fork(i, i.globals, "")
+	for path, mod := range i.modules {
+		f.modules[path] = mod
+		f.fork(i, mod.globals, path)
+	}
+	f.env = f.globals
+	if f.debugger != nil {
+		f.frames = []*frame{{function: "<script>", env: f.globals}}
+	}
+	return f
+}
+
+// fork creates the globals of f based on env, globals of i
+func (f *Interpreter) fork(i *Interpreter, env *Env, modPath string) *Env {
+	base := env.freeze()
+
+	// builtins are defined again as some of them use their interpreter,
+	// unless i replaced them
+	globals := f.newGlobals(modPath)
+	for name := range globals.values {
+		if v, ok := base.lookup(name); ok && !isBuiltin(v) {
+			delete(globals.values, name)
+		}
+	}
+	globals.base = base
+
+	f.forked[env] = globals
+	// i may be a fork itself, running functions of the interpreter it
+	// was forked from
+	for orig, e := range i.forked {
+		if e == env {
+			f.forked[orig] = globals
+		}
+	}
+	return globals
+}
+
+// globalsOf returns the globals fn runs with in i, the ones of the
+// fork when fn was declared in the interpreter i was forked from
+func (i *Interpreter) globalsOf(fn *LoxFunction) *Env {
+	if g, ok := i.forked[fn.Globals]; ok {
+		return g
+	}
+	return fn.Globals
+}
+
+// moduleGlobals returns the globals of mod in i, the ones of the fork
+// when mod was imported by the interpreter i was forked from
+func (i *Interpreter) moduleGlobals(mod *Module) *Env {
+	if g, ok := i.forked[mod.globals]; ok {
+		return g
+	}
+	return mod.globals
+}
+
+func isBuiltin(v Value) bool {
+	switch v.obj.(type) {
+	case nativeClock, *nativeFn:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+const prelude = `
+import "lib/counter.lox" as counter;
+var count = 0;
+fun bump() {
+  count = count + 1;
+  return count;
+}
+fun len(x) {
+  return "redefined";
+}
+`
+
+var preludeModules = interpreter.MapLoader{
+	"lib/counter.lox": `
+var _n = 0;
+fun next() {
+  _n = _n + 1;
+  return _n;
+}
+`,
+}
+
+func newPrelude(t *testing.T) *interpreter.Interpreter {
+	interp := interpreter.New(interpreter.WithLoader(preludeModules))
+	if err := interp.Exec(prelude); err != nil {
+		t.Fatal(err)
+	}
+	return interp
+}
+
+func run(t *testing.T, interp *interpreter.Interpreter, src string) string {
+	t.Helper()
+	var out bytes.Buffer
+	f := interp.Fork(interpreter.WithOutput(&out))
+	if err := f.Exec(src); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestForkCopyOnWrite(t *testing.T) {
+	parent := newPrelude(t)
+
+	var out1, out2 bytes.Buffer
+	f1 := parent.Fork(interpreter.WithOutput(&out1))
+	f2 := parent.Fork(interpreter.WithOutput(&out2))
+
+	src := "bump(); bump(); print bump(); print counter.next(); var mine = 1; print len(1);"
+	if err := f1.Exec(src); err != nil {
+		t.Fatal(err)
+	}
+	if err := f2.Exec("print bump(); print counter.next(); print mine;"); err == nil {
+		t.Error("a fork sees the variables defined by another one")
+	}
+	if out1.String() != "3\n1\nredefined\n" {
+		t.Errorf("first fork printed %q", out1.String())
+	}
+	if out2.String() != "1\n1\n" {
+		t.Errorf("second fork printed %q", out2.String())
+	}
+
+	// forks can be forked too
+	if got := run(t, f1, "print bump(); print mine;"); got != "4\n1\n" {
+		t.Errorf("fork of a fork printed %q", got)
+	}
+
+	// the parent is unchanged, and its own changes are not seen by
+	// existing forks
+	if err := parent.Exec("count = 10;"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f1.Exec("print count;"); err != nil {
+		t.Fatal(err)
+	}
+	if out1.String() != "3\n1\nredefined\n3\n" {
+		t.Errorf("first fork printed %q", out1.String())
+	}
+	if got := run(t, parent, "print count; print counter.next();"); got != "10\n1\n" {
+		t.Errorf("new fork printed %q", got)
+	}
+}
+
+// run with -race
+func TestForkConcurrent(t *testing.T) {
+	parent := newPrelude(t)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var out bytes.Buffer
+			f := parent.Fork(interpreter.WithOutput(&out))
+			err := f.Exec(`
+				for (var i = 0; i < 100; i = i + 1) {
+					bump();
+					counter.next();
+					random();
+				}
+				print bump();
+				print counter.next();
+			`)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if got := strings.Fields(out.String()); fmt.Sprint(got) != "[101 101]" {
+				errs <- fmt.Errorf("got %v", got)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,124 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// Callable is implemented by the values Lox can call: functions
+// declared in Lox, natives and reflected Go functions
+type Callable interface {
+	Arity() int
+	Call(c CallContext, args []Value) (Value, error)
+}
+
+// Instance is implemented by values having properties accessed with '.'
+type Instance interface {
+	Get(name string) (Value, error)
+}
+
+// Settable is implemented by instances whose properties can be
+// assigned with '.'
+type Settable interface {
+	Instance
+	Set(name string, value Value) error
+}
+
+type LoxFunction struct {
+	Declaration *parser.FunStmt
+	// globals of the module where the function was declared, it runs
+	// in the calling interpreter though, which may be a fork of the one
+	// declaring it
+	Globals *Env
+}
+
+func (l *LoxFunction) Arity() int {
+	return len(l.Declaration.Params)
+}
+
+// Call runs the function, and then each function it returns a call to
+// in tail position: their calls replace each other in a loop instead
+// of nesting
+func (l *LoxFunction) Call(c CallContext, args []Value) (Value, error) {
+	interp := c.interp
+	if interp == nil {
+		return Value{}, errNoInterpreter(l)
+	}
+
+	var tail tailFrames
+	for fn := l; ; {
+		// parameters take the first slots of the function scope
+		fnEnv := newLocalEnv(interp.globalsOf(fn), fn.Declaration.Scope)
+		copy(fnEnv.slots, args)
+
+		c, err := interp.execute([]parser.Stmt{fn.Declaration.Body}, fnEnv)
+		if err != nil {
+			tail.addTo(err)
+			return Value{}, err
+		}
+		if c.kind != tailCalled {
+			return c.value, nil
+		}
+
+		call := interp.tailCall
+		interp.tailCall = pendingCall{}
+		tail.push(call.fn, call.paren.Line)
+		fn, args = call.fn, call.args
+	}
+}
+
+func (l *LoxFunction) String() string {
+	return "<fn " + l.Declaration.Name.Lexeme + ">"
+}
+
+// maxTailFrames is the number of tail calls kept for error traces
+const maxTailFrames = 16
+
+// tailFrames remembers the last calls made in tail position, whose
+// frames don't exist anymore when an error is raised
+type tailFrames struct {
+	// oldest first, repeated calls from the same line are merged
+	frames []tailFrame
+	// number of calls that didn't fit in frames
+	dropped int
+}
+
+type tailFrame struct {
+	fn    *LoxFunction
+	line  int
+	calls int
+}
+
+func (t *tailFrames) push(fn *LoxFunction, line int) {
+	if n := len(t.frames); n > 0 && t.frames[n-1].fn == fn && t.frames[n-1].line == line {
+		t.frames[n-1].calls++
+		return
+	}
+	if len(t.frames) == maxTailFrames {
+		t.dropped += t.frames[0].calls
+		t.frames = append(t.frames[:0], t.frames[1:]...)
+	}
+	t.frames = append(t.frames, tailFrame{fn: fn, line: line, calls: 1})
+}
+
+// addTo appends the tail calls to the trace of err, innermost first,
+// like the frames of the calls they replaced
+func (t *tailFrames) addTo(err error) {
+	if len(t.frames) == 0 {
+		return
+	}
+	var trace []string
+	for i := len(t.frames) - 1; i >= 0; i-- {
+		f := t.frames[i]
+		frame := fmt.Sprintf("%v, line %d", f.fn, f.line)
+		if f.calls > 1 {
+			frame += fmt.Sprintf(" (repeated %d times)", f.calls)
+		}
+		trace = append(trace, frame)
+	}
+	if t.dropped > 0 {
+		trace = append(trace, fmt.Sprintf("... %d more tail calls", t.dropped))
+	}
+	addTrace(err, trace...)
+}
@@ -0,0 +1,55 @@
+package interpreter
+
+import (
+	"github.com/jrouviere/golox/parser"
+)
+
+// Hooks observes the execution of a program, its methods are called
+// synchronously from the interpreter goroutine. Embed NopHooks to only
+// implement some of them.
+type Hooks interface {
+	// Statement is called before executing each statement, except
+	// blocks whose statements are reported instead
+	Statement(s parser.Stmt)
+	// CallEnter is called before calling fn from the given line, and
+	// CallExit once it returned
+	CallEnter(fn Callable, args []Value, line int)
+	CallExit(fn Callable, result Value, err error)
+	// Assign is called when Env.Set changes the value of an existing
+	// variable
+	Assign(name string, value Value)
+	// Error is called once for each runtime error or thrown value, by
+	// the innermost statement raising it, even if it's later caught
+	Error(err error)
+}
+
+// NopHooks implements Hooks by doing nothing
+type NopHooks struct{}
+
+func (NopHooks) Statement(parser.Stmt)            {}
+func (NopHooks) CallEnter(Callable, []Value, int) {}
+func (NopHooks) CallExit(Callable, Value, error)  {}
+func (NopHooks) Assign(string, Value)             {}
+func (NopHooks) Error(error)                      {}
+
+// WithHooks reports the execution of programs to h
+func WithHooks(h Hooks) Option {
+	return func(i *Interpreter) {
+		i.hooks = h
+	}
+}
+
+// raised reports err to the hooks the first time it goes through a
+// statement
+func (i *Interpreter) raised(err error) {
+	switch err.(type) {
+	case *RuntimeError, *Thrown:
+	default:
+		return
+	}
+	if err == i.lastRaised {
+		return
+	}
+	i.lastRaised = err
+	i.hooks.Error(err)
+}
@@ -0,0 +1,124 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jrouviere/golox/interpreter"
+	"github.com/jrouviere/golox/parser"
+)
+
+// recorder logs the hook calls in a compact form
+type recorder struct {
+	interpreter.NopHooks
+	log []string
+}
+
+func (r *recorder) Statement(s parser.Stmt) {
+	r.log = append(r.log, fmt.Sprintf("stmt %d", s.Line()))
+}
+
+func (r *recorder) CallEnter(fn interpreter.Callable, args []interpreter.Value, line int) {
+	r.log = append(r.log, fmt.Sprintf("enter %v %v line %d", fn, args, line))
+}
+
+func (r *recorder) CallExit(fn interpreter.Callable, result interpreter.Value, err error) {
+	r.log = append(r.log, fmt.Sprintf("exit %v %v %v", fn, result, err))
+}
+
+func (r *recorder) Assign(name string, value interpreter.Value) {
+	r.log = append(r.log, fmt.Sprintf("assign %s %v", name, value))
+}
+
+func (r *recorder) Error(err error) {
+	r.log = append(r.log, "error "+err.Error())
+}
+
+const hooksSrc = `fun double(x) {
+  return x * 2;
+}
+var a = 1;
+a = double(a);
+try {
+  a = a + nil;
+} catch (e) {
+  print e.message;
+}
+`
+
+func TestHooks(t *testing.T) {
+	r := &recorder{}
+	var out bytes.Buffer
+	interp := interpreter.New(interpreter.WithOutput(&out), interpreter.WithHooks(r))
+	if err := interp.Exec(hooksSrc); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"stmt 1",
+		"stmt 4",
+		"stmt 5",
+		"enter <fn double> [1] line 5",
+		"stmt 2",
+		"exit <fn double> 2 <nil>",
+		"assign a 2",
+		"stmt 6",
+		"stmt 7",
+		"error runtime error: unimplemented operation number + nil, line 7",
+		"stmt 9",
+	}
+	if !reflect.DeepEqual(r.log, expected) {
+		t.Errorf("unexpected hook calls:\n%s", strings.Join(r.log, "\n"))
+	}
+}
+
+func TestJSONTracer(t *testing.T) {
+	var trace bytes.Buffer
+	tracer := interpreter.NewJSONTracer(&trace)
+	interp := interpreter.New(interpreter.WithOutput(&bytes.Buffer{}), interpreter.WithHooks(tracer))
+	if err := interp.Exec(hooksSrc); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracer.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	if len(lines) != 11 {
+		t.Fatalf("expected one event per hook call, got:\n%s", trace.String())
+	}
+
+	var events []map[string]interface{}
+	for _, l := range lines {
+		var ev map[string]interface{}
+		if err := json.Unmarshal([]byte(l), &ev); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", l, err)
+		}
+		delete(ev, "time")
+		events = append(events, ev)
+	}
+
+	call := map[string]interface{}{
+		"seq": 4.0, "event": "call", "depth": 0.0, "line": 5.0,
+		"function": "double", "args": []interface{}{1.0},
+	}
+	if !reflect.DeepEqual(events[3], call) {
+		t.Errorf("unexpected call event: %v", events[3])
+	}
+	if events[4]["depth"] != 1.0 || events[4]["kind"] != "ReturnStmt" {
+		t.Errorf("unexpected statement event: %v", events[4])
+	}
+	if events[5]["event"] != "return" || events[5]["result"] != 2.0 {
+		t.Errorf("unexpected return event: %v", events[5])
+	}
+	if events[6]["event"] != "assign" || events[6]["name"] != "a" || events[6]["value"] != 2.0 {
+		t.Errorf("unexpected assign event: %v", events[6])
+	}
+	if events[9]["event"] != "error" || events[9]["kind"] != "runtime" || events[9]["line"] != 7.0 {
+		t.Errorf("unexpected error event: %v", events[9])
+	}
+}
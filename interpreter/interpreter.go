@@ -1,57 +1,139 @@
 package interpreter
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
 	"time"
-
-	"github.com/jrouviere/golox/parser"
 )
 
 type Interpreter struct {
-	env *parser.Env
+	globals *Env
+	// environment of the scope being executed
+	env *Env
+	// call left for the caller by a return in tail position
+	tailCall pendingCall
+	// given to ExecContext, seen by natives through CallContext
+	ctx context.Context
+
+	rand *rand.Rand
+	io   *IOConfig
+	out  io.Writer
+
+	optimize bool
+
+	loader  Loader
+	modules map[string]*Module
+	loading []string
+
+	// globals of the interpreter this one was forked from, and of its
+	// modules, mapped to the ones replacing them
+	forked map[*Env]*Env
+	forkMu sync.Mutex
+
+	debugger *Debugger
+	// Lox call stack, only tracked when debugging
+	frames []*frame
+
+	profiler *Profiler
+
+	coverage  *Coverage
+	coverFile string
+
+	hooks Hooks
+	// last error reported to the hooks, while it propagates
+	lastRaised error
 }
 
-func New() *Interpreter {
-	globals := parser.NewEnv(nil)
-	globals.Define("clock", nativeClock{})
-	return &Interpreter{
-		env: globals,
+// Option configures optional features of an Interpreter
+type Option func(*Interpreter)
+
+// WithOutput redirects the output of print statements to w
+func WithOutput(w io.Writer) Option {
+	return func(i *Interpreter) {
+		i.out = w
 	}
 }
 
-func (i *Interpreter) Run(input string) {
-	scanner := parser.NewScanner(input)
-	tokens, err := scanner.Scan()
-	if err != nil {
-		fmt.Println("Error", err)
-		return
+// WithOptimizer folds constant expressions and removes dead branches
+// before running a program, see parser.Optimize
+func WithOptimizer() Option {
+	return func(i *Interpreter) {
+		i.optimize = true
+	}
+}
+
+func New(opts ...Option) *Interpreter {
+	interp := &Interpreter{
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		out:     os.Stdout,
+		modules: make(map[string]*Module),
 	}
-	for _, t := range tokens {
-		fmt.Println(t.Line, t)
+	for _, opt := range opts {
+		opt(interp)
 	}
 
-	expr, err := parser.New(tokens).Parse()
-	if err != nil {
+	interp.globals = interp.newGlobals("")
+	interp.env = interp.globals
+	if interp.debugger != nil {
+		interp.frames = []*frame{{function: "<script>", env: interp.globals}}
+	}
+	return interp
+}
+
+// newGlobals creates the root environment of a module, with all the
+// builtins defined
+func (i *Interpreter) newGlobals(modPath string) *Env {
+	globals := NewEnv(nil)
+	globals.hooks = i.hooks
+	globals.Define("clock", Object(nativeClock{}))
+	i.defineStdlib(globals)
+	if i.io != nil {
+		i.defineIO(globals)
+	}
+	globals.importer = &moduleImporter{interp: i, from: modPath}
+	return globals
+}
+
+// Globals returns the root environment, where the builtins and the
+// top level declarations live
+func (i *Interpreter) Globals() *Env {
+	return i.globals
+}
+
+// Run executes input and prints any error on stdout
+func (i *Interpreter) Run(input string) {
+	if err := i.Exec(input); err != nil {
 		fmt.Println("Error", err)
-		return
 	}
-	for _, e := range expr {
-		fmt.Println(e)
+}
+
+// Exec scans, parses and executes input in the interpreter globals,
+// the first error encountered stops the execution and is returned
+func (i *Interpreter) Exec(input string) error {
+	p, err := i.Compile(input)
+	if err != nil {
+		return err
 	}
+	return i.ExecProgram(p)
+}
 
-	for _, e := range expr {
-		err := e.Evaluate(i.env)
-		if err != nil {
-			fmt.Println("Error", err)
-			return
-		}
+// ExecContext is like Exec, natives called by input receive ctx
+func (i *Interpreter) ExecContext(ctx context.Context, input string) error {
+	p, err := i.Compile(input)
+	if err != nil {
+		return err
 	}
+	return i.ExecProgramContext(ctx, p)
 }
 
 type nativeClock struct{}
 
-func (nativeClock) Call(env *parser.Env, args []interface{}) (interface{}, error) {
-	return float64(time.Now().UnixMilli()) / 1000.0, nil
+func (nativeClock) Call(c CallContext, args []Value) (Value, error) {
+	return Number(float64(time.Now().UnixMilli()) / 1000.0), nil
 }
 func (nativeClock) Arity() int {
 	return 0
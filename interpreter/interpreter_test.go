@@ -0,0 +1,187 @@
+package interpreter_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jrouviere/golox/interpreter"
+	"github.com/jrouviere/golox/parser"
+)
+
+// Each .lox file under testdata is executed and its output compared with
+// the expectations written in comments:
+//
+//	print 1 + 2; // expect: 3
+//	print nope;  // expect runtime error: undefined variable nope
+//
+// Error expectations (runtime error, syntax error, scan error) also check
+// that the error is reported on the line of the comment.
+var expectRe = regexp.MustCompile(`// expect( runtime error| syntax error| scan error)?: ?(.*)$`)
+
+type expectations struct {
+	output  []string
+	errKind string
+	errMsg  string
+	errLine int
+}
+
+func parseExpectations(t *testing.T, path string) expectations {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var exp expectations
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		m := expectRe.FindStringSubmatch(sc.Text())
+		if m == nil {
+			continue
+		}
+		if m[1] == "" {
+			exp.output = append(exp.output, m[2])
+			continue
+		}
+		if exp.errKind != "" {
+			t.Fatalf("%s:%d: only one error can be expected per file", path, line)
+		}
+		exp.errKind = strings.TrimSpace(m[1])
+		exp.errMsg = m[2]
+		exp.errLine = line
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return exp
+}
+
+// describeError returns the kind, message and line of an error
+// returned by Interpreter.Exec
+func describeError(err error) (string, string, int) {
+	var rerr *interpreter.RuntimeError
+	var thrown *interpreter.Thrown
+	var serr *parser.SyntaxError
+	var scerr *parser.ScanningError
+
+	switch {
+	case errors.As(err, &rerr):
+		return "runtime error", rerr.Msg, rerr.Line
+	case errors.As(err, &thrown):
+		return "runtime error", "uncaught exception: " + interpreter.Stringify(thrown.Value), thrown.Line
+	case errors.As(err, &serr):
+		return "syntax error", serr.Msg, serr.Token.Line
+	case errors.As(err, &scerr):
+		return "scan error", scerr.Msg, scerr.Line
+	}
+	return fmt.Sprintf("%T", err), err.Error(), 0
+}
+
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/*/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no test files found")
+	}
+
+	for _, path := range files {
+		path := path
+		t.Run(strings.TrimSuffix(strings.TrimPrefix(path, "testdata/"), ".lox"), func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exp := parseExpectations(t, path)
+
+			var out bytes.Buffer
+			interp := interpreter.New(
+				interpreter.WithOutput(&out),
+				interpreter.WithLoader(interpreter.FSLoader{FS: os.DirFS("testdata")}),
+			)
+			err = interp.Exec(string(src))
+
+			var output []string
+			if out.Len() > 0 {
+				output = strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+			}
+			if strings.Join(output, "\n") != strings.Join(exp.output, "\n") {
+				t.Errorf("output mismatch\n--- got:\n%s\n--- expected:\n%s",
+					strings.Join(output, "\n"), strings.Join(exp.output, "\n"))
+			}
+
+			if err == nil {
+				if exp.errKind != "" {
+					t.Errorf("expected %s %q on line %d, got no error", exp.errKind, exp.errMsg, exp.errLine)
+				}
+				return
+			}
+
+			kind, msg, line := describeError(err)
+			if exp.errKind == "" {
+				t.Errorf("unexpected %s on line %d: %s", kind, line, msg)
+				return
+			}
+			if kind != exp.errKind || msg != exp.errMsg || line != exp.errLine {
+				t.Errorf("error mismatch\ngot:      %s %q on line %d\nexpected: %s %q on line %d",
+					kind, msg, line, exp.errKind, exp.errMsg, exp.errLine)
+			}
+		})
+	}
+}
+
+// The optimizer must not change what programs do: every golden file
+// gives the same output and error with and without it
+func TestGoldenOptimized(t *testing.T) {
+	files, err := filepath.Glob("testdata/*/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range files {
+		path := path
+		t.Run(strings.TrimSuffix(strings.TrimPrefix(path, "testdata/"), ".lox"), func(t *testing.T) {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := execGolden(string(src))
+			optOut, optErr := execGolden(string(src), interpreter.WithOptimizer())
+
+			if optOut != out {
+				t.Errorf("output mismatch\n--- optimized:\n%s--- expected:\n%s", optOut, out)
+			}
+			if (err == nil) != (optErr == nil) {
+				t.Fatalf("optimized error %v, expected %v", optErr, err)
+			}
+			if err == nil {
+				return
+			}
+			kind, msg, line := describeError(err)
+			optKind, optMsg, optLine := describeError(optErr)
+			if optKind != kind || optMsg != msg || optLine != line {
+				t.Errorf("error mismatch\noptimized: %s %q on line %d\nexpected:  %s %q on line %d",
+					optKind, optMsg, optLine, kind, msg, line)
+			}
+		})
+	}
+}
+
+// execGolden runs a golden file and returns its output and error
+func execGolden(src string, opts ...interpreter.Option) (string, error) {
+	var out bytes.Buffer
+	interp := interpreter.New(append(opts,
+		interpreter.WithOutput(&out),
+		interpreter.WithLoader(interpreter.FSLoader{FS: os.DirFS("testdata")}),
+	)...)
+	err := interp.Exec(src)
+	return out.String(), err
+}
@@ -0,0 +1,210 @@
+package interpreter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// IOConfig describes what the I/O natives are allowed to do
+type IOConfig struct {
+	// Root restricts readFile and writeFile to files below this
+	// directory, relative paths are resolved from it. An empty Root
+	// gives access to the whole file system.
+	Root string
+	// Env lists the environment variables getenv can read, reading
+	// another one is an error. A nil Env gives access to all of them.
+	Env []string
+	// Args is the list returned by args()
+	Args []string
+	// Stdin is read by readLine, os.Stdin if nil
+	Stdin io.Reader
+
+	stdin *bufio.Reader
+	// forks of an interpreter read the same stdin
+	stdinMu *sync.Mutex
+}
+
+// WithIO enables the I/O natives: readFile, writeFile, readLine, getenv
+// and args. They are not defined at all without this option, so
+// sandboxed scripts can't reach the host. Root only restricts the
+// files, the environment variables are restricted by Env.
+func WithIO(cfg IOConfig) Option {
+	return func(i *Interpreter) {
+		if cfg.Stdin == nil {
+			cfg.Stdin = os.Stdin
+		}
+		cfg.stdin = bufio.NewReader(cfg.Stdin)
+		cfg.stdinMu = &sync.Mutex{}
+		i.io = &cfg
+	}
+}
+
+func (i *Interpreter) defineIO(env *Env) {
+	cfg := i.io
+	natives := []*nativeFn{
+		{"readFile", 1, func(args []Value) (Value, error) {
+			f, err := cfg.open("readFile", args, os.O_RDONLY)
+			if err != nil {
+				return Value{}, err
+			}
+			defer f.Close()
+			b, err := io.ReadAll(f)
+			if err != nil {
+				return Value{}, ioError("readFile", err)
+			}
+			return String(string(b)), nil
+		}},
+		{"writeFile", 2, func(args []Value) (Value, error) {
+			content, err := argString("writeFile", args, 1)
+			if err != nil {
+				return Value{}, err
+			}
+			// not truncated on open, the file is only known to be
+			// below the root once it is open
+			f, err := cfg.open("writeFile", args, os.O_WRONLY|os.O_CREATE)
+			if err != nil {
+				return Value{}, err
+			}
+			err = f.Truncate(0)
+			if err == nil {
+				_, err = f.WriteString(content)
+			}
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return Value{}, ioError("writeFile", err)
+			}
+			return Value{}, nil
+		}},
+		{"readLine", 0, func(args []Value) (Value, error) {
+			cfg.stdinMu.Lock()
+			line, err := cfg.stdin.ReadString('\n')
+			cfg.stdinMu.Unlock()
+			if err == io.EOF && line == "" {
+				return Value{}, nil
+			}
+			if err != nil && err != io.EOF {
+				return Value{}, ioError("readLine", err)
+			}
+			return String(strings.TrimRight(line, "\r\n")), nil
+		}},
+		{"getenv", 1, func(args []Value) (Value, error) {
+			name, err := argString("getenv", args, 0)
+			if err != nil {
+				return Value{}, err
+			}
+			if !cfg.envAllowed(name) {
+				return Value{}, &RuntimeError{Msg: fmt.Sprintf("getenv: access to %q denied", name)}
+			}
+			if v, ok := os.LookupEnv(name); ok {
+				return String(v), nil
+			}
+			return Value{}, nil
+		}},
+		{"args", 0, func(args []Value) (Value, error) {
+			lst := &List{}
+			for _, a := range cfg.Args {
+				lst.Elems = append(lst.Elems, String(a))
+			}
+			return Object(lst), nil
+		}},
+	}
+
+	for _, n := range natives {
+		env.Define(n.name, Object(n))
+	}
+}
+
+func (cfg *IOConfig) envAllowed(name string) bool {
+	if cfg.Env == nil {
+		return true
+	}
+	for _, n := range cfg.Env {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// open opens the file given as first argument, after checking it
+// doesn't escape the allowed root directory. The path is checked before
+// opening it, then the opened file is checked again, since a symlink
+// could be swapped in between. A symlink as last component of the path
+// is never followed, even when it is dangling.
+func (cfg *IOConfig) open(name string, args []Value, flag int) (*os.File, error) {
+	path, err := argString(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Root == "" {
+		f, err := os.OpenFile(path, flag, 0o644)
+		if err != nil {
+			return nil, ioError(name, err)
+		}
+		return f, nil
+	}
+
+	root, err := filepath.Abs(cfg.Root)
+	if err != nil {
+		return nil, ioError(name, err)
+	}
+	if r, err := filepath.EvalSymlinks(root); err == nil {
+		root = r
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.Root, path)
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, ioError(name, err)
+	}
+	// follow the symlinks of the directory so a link inside root
+	// can't point outside of it, the file may not exist yet
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+		path = filepath.Join(dir, filepath.Base(path))
+	}
+	if !within(root, path) {
+		return nil, cfg.denied(name, args)
+	}
+
+	f, err := openNoFollow(path, flag)
+	if err != nil {
+		if fi, lerr := os.Lstat(path); lerr == nil && fi.Mode()&os.ModeSymlink != 0 {
+			return nil, cfg.denied(name, args)
+		}
+		return nil, ioError(name, err)
+	}
+	opened, err := openedPath(f)
+	if err != nil {
+		f.Close()
+		return nil, ioError(name, err)
+	}
+	if !within(root, opened) {
+		f.Close()
+		return nil, cfg.denied(name, args)
+	}
+	return f, nil
+}
+
+// within reports whether path is root or below it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (cfg *IOConfig) denied(name string, args []Value) error {
+	return &RuntimeError{
+		Msg: fmt.Sprintf("%s: access to %q denied, outside of %q", name, args[0], cfg.Root),
+	}
+}
+
+func ioError(name string, err error) error {
+	return &RuntimeError{Msg: fmt.Sprintf("%s: %v", name, err)}
+}
@@ -0,0 +1,18 @@
+package interpreter
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// openNoFollow opens path, failing if its last component is a symlink
+func openNoFollow(path string, flag int) (*os.File, error) {
+	return os.OpenFile(path, flag|syscall.O_NOFOLLOW, 0o644)
+}
+
+// openedPath returns the path of the file f refers to, as seen by the
+// kernel: it can't be changed by renaming or linking after the open
+func openedPath(f *os.File) (string, error) {
+	return os.Readlink("/proc/self/fd/" + strconv.Itoa(int(f.Fd())))
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package interpreter
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// openNoFollow opens path, failing if its last component is a symlink.
+// Without O_NOFOLLOW the link is looked for before opening, which
+// openedPath makes up for.
+func openNoFollow(path string, flag int) (*os.File, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrPermission}
+	}
+	return os.OpenFile(path, flag, 0o644)
+}
+
+// openedPath returns the path of the file f refers to, following its
+// symlinks again once it is open
+func openedPath(f *os.File) (string, error) {
+	return filepath.EvalSymlinks(f.Name())
+}
@@ -0,0 +1,117 @@
+package interpreter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIOSandbox(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	for _, d := range []string{root, outside} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "in.txt"), []byte("inside"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(outside, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "dirlink")); err != nil {
+		t.Fatal(err)
+	}
+	// a link to a file that doesn't exist yet, outside of the root
+	if err := os.Symlink(filepath.Join(outside, "ghost.txt"), filepath.Join(root, "dangling.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	interp := New(WithIO(IOConfig{Root: root}))
+	err := run(interp, `
+		var relative = readFile("in.txt");
+		var absolute = readFile("`+filepath.Join(root, "in.txt")+`");
+		writeFile("new.txt", "created");
+		var created = readFile("new.txt");
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"relative": "inside", "absolute": "inside", "created": "created"} {
+		if got := global(t, interp, name); got != want {
+			t.Errorf("%s: got %q, expected %q", name, got, want)
+		}
+	}
+
+	for _, src := range []string{
+		`readFile("../outside/secret.txt");`,
+		`readFile("` + secret + `");`,
+		`readFile("link.txt");`,
+		`readFile("dirlink/secret.txt");`,
+		`readFile("dangling.txt");`,
+		`writeFile("../escape.txt", "x");`,
+		`writeFile("dirlink/new.txt", "x");`,
+		`writeFile("dangling.txt", "x");`,
+	} {
+		err := run(interp, src)
+		if err == nil || !strings.Contains(err.Error(), "denied") {
+			t.Errorf("%s: got error %v", src, err)
+		}
+	}
+	for _, name := range []string{"escape.txt", "outside/new.txt", "outside/ghost.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s was written outside of the root", name)
+		}
+	}
+}
+
+func TestIOStdinEnvArgs(t *testing.T) {
+	t.Setenv("GOLOX_TEST", "yes")
+	t.Setenv("GOLOX_HIDDEN", "no")
+
+	interp := New(WithIO(IOConfig{
+		Stdin: strings.NewReader("first\r\nlast"),
+		Env:   []string{"GOLOX_TEST", "GOLOX_UNSET"},
+		Args:  []string{"a", "b"},
+	}))
+	err := run(interp, `
+		var first = readLine();
+		var last = readLine();
+		var eof = readLine();
+		var set = getenv("GOLOX_TEST");
+		var unset = getenv("GOLOX_UNSET");
+		var arguments = args();
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"first":     "first",
+		"last":      "last",
+		"eof":       "nil",
+		"set":       "yes",
+		"unset":     "nil",
+		"arguments": "[a, b]",
+	} {
+		if got := global(t, interp, name); got != want {
+			t.Errorf("%s: got %q, expected %q", name, got, want)
+		}
+	}
+
+	err = run(interp, `getenv("GOLOX_HIDDEN");`)
+	if err == nil || err.Error() != `runtime error: getenv: access to "GOLOX_HIDDEN" denied, line 1` {
+		t.Errorf("got error %v", err)
+	}
+
+	// without WithIO the natives don't exist
+	if err := run(New(), `readFile("x");`); err == nil {
+		t.Error("readFile is defined without WithIO")
+	}
+}
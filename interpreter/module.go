@@ -0,0 +1,196 @@
+package interpreter
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// Loader returns the source code of the module at path, paths are
+// slash separated and already resolved from the importing module
+type Loader interface {
+	Load(path string) (string, error)
+}
+
+// WithLoader enables import statements, modules are read from l.
+// Without a loader every import fails.
+func WithLoader(l Loader) Option {
+	return func(i *Interpreter) {
+		i.loader = l
+	}
+}
+
+// FSLoader loads modules from a file system, like os.DirFS or an embed.FS
+type FSLoader struct {
+	FS fs.FS
+}
+
+func (l FSLoader) Load(path string) (string, error) {
+	b, err := fs.ReadFile(l.FS, path)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// MapLoader serves modules from memory, keyed by path
+type MapLoader map[string]string
+
+func (l MapLoader) Load(path string) (string, error) {
+	src, ok := l[path]
+	if !ok {
+		return "", fs.ErrNotExist
+	}
+	return src, nil
+}
+
+// Module is the namespace value bound by an import statement. It
+// exposes the names declared by the top-level statements of the module,
+// except the ones starting with an underscore which stay private.
+type Module struct {
+	Path string
+	// exported names, read from globals on each access so importers
+	// see their current value
+	exports map[string]bool
+	// where the module's functions were declared
+	globals *Env
+}
+
+func (m *Module) Get(name string) (Value, error) {
+	return m.get(m.globals, name)
+}
+
+// get reads name from globals, the ones of m or of a fork of them
+func (m *Module) get(globals *Env, name string) (Value, error) {
+	if m.exports[name] {
+		if v, err := globals.Get(name); err == nil {
+			return v, nil
+		}
+	}
+	return Value{}, &RuntimeError{
+		Msg: fmt.Sprintf("module %q has no exported name %s", m.Path, name),
+	}
+}
+
+func (m *Module) String() string {
+	return "<module " + m.Path + ">"
+}
+
+// moduleImporter resolves imports relative to the module being executed
+type moduleImporter struct {
+	interp *Interpreter
+	from   string
+}
+
+func (imp *moduleImporter) Import(p string) (*Module, error) {
+	if !path.IsAbs(p) {
+		p = path.Join(path.Dir(imp.from), p)
+	}
+	return imp.interp.importModule(path.Clean(strings.TrimPrefix(p, "/")))
+}
+
+func (i *Interpreter) importModule(modPath string) (*Module, error) {
+	if mod, ok := i.modules[modPath]; ok {
+		return mod, nil
+	}
+	if i.loader == nil {
+		return nil, &RuntimeError{Msg: "import is disabled, no module loader configured"}
+	}
+
+	for idx, p := range i.loading {
+		if p == modPath {
+			cycle := append(i.loading[idx:], modPath)
+			return nil, &RuntimeError{
+				Msg: "import cycle: " + strings.Join(cycle, " -> "),
+			}
+		}
+	}
+	i.loading = append(i.loading, modPath)
+	defer func() { i.loading = i.loading[:len(i.loading)-1] }()
+
+	src, err := i.loader.Load(modPath)
+	if err != nil {
+		return nil, &RuntimeError{Msg: fmt.Sprintf("cannot load module %q: %v", modPath, err)}
+	}
+
+	stmts, err := i.parse(src)
+	if err != nil {
+		return nil, moduleError(modPath, err)
+	}
+	if i.coverage != nil {
+		i.coverage.register(i.moduleFile(modPath), src, stmts)
+	}
+
+	globals := i.newGlobals(modPath)
+	if _, err := i.execute(stmts, globals); err != nil {
+		return nil, moduleError(modPath, err)
+	}
+
+	mod := &Module{
+		Path:    modPath,
+		exports: make(map[string]bool),
+		globals: globals,
+	}
+	for _, s := range stmts {
+		var name *parser.Token
+		switch s := s.(type) {
+		case *parser.VarDecl:
+			name = s.Name
+		case *parser.FunStmt:
+			name = s.Name
+		case *parser.ImportStmt:
+			name = s.Name
+		}
+		if name != nil && !strings.HasPrefix(name.Lexeme, "_") {
+			mod.exports[name.Lexeme] = true
+		}
+	}
+	i.modules[modPath] = mod
+	return mod, nil
+}
+
+// ModuleError is an error raised while loading or running an imported
+// module, Err is the original error so a value thrown by the module can
+// still be caught by the importer
+type ModuleError struct {
+	Path string
+	// line of the import statement
+	Line int
+	Err  error
+}
+
+func (e *ModuleError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s (imported line %d): %v", e.Path, e.Line, e.Err)
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *ModuleError) Unwrap() error {
+	return e.Err
+}
+
+// moduleError reports err as happening inside the module at modPath
+func moduleError(modPath string, err error) error {
+	return &ModuleError{Path: modPath, Err: err}
+}
+
+// parse returns the statements of input, ready to be executed
+func (i *Interpreter) parse(input string) ([]parser.Stmt, error) {
+	tokens, err := parser.NewScanner(input).Scan()
+	if err != nil {
+		return nil, err
+	}
+	stmts, err := parser.New(tokens).Parse()
+	if err != nil {
+		return nil, err
+	}
+	if i.optimize {
+		stmts = parser.Optimize(stmts)
+	}
+	parser.Resolve(stmts)
+	return stmts, nil
+}
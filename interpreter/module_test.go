@@ -0,0 +1,203 @@
+package interpreter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testModules = MapLoader{
+	"main.lox": `
+import "lib/util.lox" as util;
+var twice = util.double(util.base);
+`,
+	"lib/util.lox": `
+import "math.lox" as m;
+var base = m.square(3);
+var _hidden = 1;
+fun double(x) {
+  return x * 2;
+}
+`,
+	"lib/math.lox": `
+fun square(x) {
+  return x * x;
+}
+`,
+	"lib/counter.lox": `
+var count = 0;
+fun inc() {
+  count = count + 1;
+  return count;
+}
+`,
+	"lib/names.lox": `
+fun get() {
+  return "mine";
+}
+var len = 3;
+{
+  var inner = 1;
+}
+`,
+	"throws.lox":  `throw "boom";`,
+	"cycle/a.lox": `import "b.lox" as b;`,
+	"cycle/b.lox": `import "a.lox" as a;`,
+	"broken.lox":  `var x = 1 + nil;`,
+}
+
+// countingLoader counts the loads of each module
+type countingLoader map[string]int
+
+func (l countingLoader) Load(path string) (string, error) {
+	l[path]++
+	return testModules.Load(path)
+}
+
+func TestImport(t *testing.T) {
+	loads := countingLoader{}
+	interp := New(WithLoader(loads))
+	err := run(interp, `
+		import "main.lox" as main;
+		import "lib/util.lox" as u;
+		import "/lib/math.lox" as math;
+		var twice = main.twice;
+		var base = u.base;
+		var square = math.square(4);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"twice":  "18",
+		"base":   "9",
+		"square": "16",
+	} {
+		if got := global(t, interp, name); got != want {
+			t.Errorf("%s: got %q, expected %q", name, got, want)
+		}
+	}
+	// each module is loaded once, however it is imported
+	for _, path := range []string{"main.lox", "lib/util.lox", "lib/math.lox"} {
+		if loads[path] != 1 {
+			t.Errorf("%s loaded %d times", path, loads[path])
+		}
+	}
+}
+
+func TestImportNames(t *testing.T) {
+	interp := New(WithLoader(testModules))
+	err := run(interp, `
+		import "lib/names.lox" as n;
+		var got = n.get();
+		var length = n.len;
+		var as = 2;
+		import "lib/math.lox" as m;
+		var square = m.square(as);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// definitions named like builtins are exported, as is only a
+	// keyword in imports
+	for name, want := range map[string]string{
+		"got":    "mine",
+		"length": "3",
+		"square": "4",
+	} {
+		if got := global(t, interp, name); got != want {
+			t.Errorf("%s: got %q, expected %q", name, got, want)
+		}
+	}
+}
+
+func TestImportModuleErrors(t *testing.T) {
+	interp := New(WithLoader(testModules))
+	err := run(interp, `
+		var caught;
+		try {
+			import "throws.lox" as t;
+		} catch (e) {
+			caught = e;
+		}
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := global(t, interp, "caught"); got != "boom" {
+		t.Errorf("caught %q, expected %q", got, "boom")
+	}
+
+	// the error keeps its kind and the line where it happened
+	err = run(New(WithLoader(testModules)), "\nimport \"broken.lox\" as b;")
+	var merr *ModuleError
+	var rerr *RuntimeError
+	if !errors.As(err, &merr) || merr.Path != "broken.lox" || merr.Line != 2 {
+		t.Fatalf("got error %#v", err)
+	}
+	if !errors.As(err, &rerr) || rerr.Line != 1 {
+		t.Errorf("got error %#v", err)
+	}
+}
+
+func TestImportLiveBindings(t *testing.T) {
+	interp := New(WithLoader(testModules))
+	err := run(interp, `
+		import "lib/counter.lox" as c;
+		var before = c.count;
+		c.inc();
+		var once = c.count;
+		c.inc();
+		var twice = c.count;
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a fork sees the module as it was, and changes its own copy
+	fork := interp.Fork()
+	if err := run(fork, `c.inc(); var forked = c.count;`); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(interp, `var after = c.count;`); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"before": "0",
+		"once":   "1",
+		"twice":  "2",
+		"after":  "2",
+	} {
+		if got := global(t, interp, name); got != want {
+			t.Errorf("%s: got %q, expected %q", name, got, want)
+		}
+	}
+	if got := global(t, fork, "forked"); got != "3" {
+		t.Errorf("forked: got %q, expected %q", got, "3")
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src string
+		err string
+	}{
+		{`import "lib/util.lox" as u; u._hidden;`, `module "lib/util.lox" has no exported name _hidden`},
+		{`import "lib/util.lox" as u; u.nope;`, `module "lib/util.lox" has no exported name nope`},
+		{`import "missing.lox" as m;`, `cannot load module "missing.lox"`},
+		{`import "cycle/a.lox" as a;`, "import cycle: cycle/a.lox -> cycle/b.lox -> cycle/a.lox"},
+		{`import "broken.lox" as b;`, "broken.lox (imported line 1): runtime error: unimplemented operation"},
+		{`import "lib/names.lox" as n; n.inner;`, `module "lib/names.lox" has no exported name inner`},
+		{`import "lib/names.lox" as n; n.clock;`, `module "lib/names.lox" has no exported name clock`},
+		{`var x = 1; x.y;`, "have properties"},
+	} {
+		err := run(New(WithLoader(testModules)), tc.src)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got error %v, expected %q", tc.src, err, tc.err)
+		}
+	}
+
+	err := run(New(), `import "main.lox" as main;`)
+	if err == nil || !strings.Contains(err.Error(), "no module loader configured") {
+		t.Errorf("got error %v without a loader", err)
+	}
+}
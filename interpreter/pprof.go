@@ -0,0 +1,197 @@
+package interpreter
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format
+// read by go tool pprof. Each sample is a call stack with the line
+// being executed in every frame, its values are the number of calls
+// and the time spent at the innermost line.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	b := &pprofBuilder{
+		strings:   map[string]int64{"": 0},
+		table:     []string{""},
+		functions: make(map[*FuncStats]uint64),
+		locations: make(map[lineKey]uint64),
+	}
+
+	var prof protoBuf
+	// sample types
+	for _, st := range [][2]string{{"calls", "count"}, {"time", "nanoseconds"}} {
+		var vt protoBuf
+		vt.int64(1, b.str(st[0]))
+		vt.int64(2, b.str(st[1]))
+		prof.bytes(1, vt)
+	}
+
+	p.walk(func(n *callNode) {
+		// the call itself is counted on the line of the declaration,
+		// the time on the lines executed
+		samples := map[int][2]int64{n.fn.Line: {int64(n.calls), 0}}
+		for line, d := range n.self {
+			s := samples[line]
+			s[1] += d.Nanoseconds()
+			samples[line] = s
+		}
+
+		var lines []int
+		for l := range samples {
+			lines = append(lines, l)
+		}
+		sort.Ints(lines)
+		for _, line := range lines {
+			vals := samples[line]
+			if vals == [2]int64{} {
+				continue
+			}
+
+			var locs []uint64
+			locs = append(locs, b.location(n.fn, line))
+			for c := n; c.parent != nil; c = c.parent {
+				locs = append(locs, b.location(c.parent.fn, c.callLine))
+			}
+
+			var sample protoBuf
+			sample.packedUint64(1, locs)
+			sample.packedInt64(2, vals[:])
+			prof.bytes(2, sample)
+		}
+	})
+
+	prof.raw = append(prof.raw, b.locs.raw...)
+	prof.raw = append(prof.raw, b.funcs.raw...)
+	for _, s := range b.table {
+		prof.string(6, s)
+	}
+	if !p.start.IsZero() {
+		prof.int64(9, p.start.UnixNano())
+	}
+	prof.int64(10, p.total.Nanoseconds())
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(prof.raw); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// pprofBuilder assigns the ids of strings, functions and locations
+type pprofBuilder struct {
+	strings map[string]int64
+	table   []string
+
+	functions map[*FuncStats]uint64
+	funcs     protoBuf
+	locations map[lineKey]uint64
+	locs      protoBuf
+}
+
+func (b *pprofBuilder) str(s string) int64 {
+	id, ok := b.strings[s]
+	if !ok {
+		id = int64(len(b.table))
+		b.strings[s] = id
+		b.table = append(b.table, s)
+	}
+	return id
+}
+
+func (b *pprofBuilder) function(fn *FuncStats) uint64 {
+	id, ok := b.functions[fn]
+	if !ok {
+		id = uint64(len(b.functions) + 1)
+		b.functions[fn] = id
+
+		// pprof hides names between angle brackets, like <script>
+		name := strings.TrimSuffix(strings.TrimPrefix(fn.Name, "<"), ">")
+
+		var f protoBuf
+		f.uint64(1, id)
+		f.int64(2, b.str(name))
+		f.int64(3, b.str(fn.Name))
+		f.int64(5, int64(fn.Line))
+		b.funcs.bytes(5, f)
+	}
+	return id
+}
+
+func (b *pprofBuilder) location(fn *FuncStats, line int) uint64 {
+	k := lineKey{fn, line}
+	id, ok := b.locations[k]
+	if !ok {
+		id = uint64(len(b.locations) + 1)
+		b.locations[k] = id
+
+		var l protoBuf
+		l.uint64(1, b.function(fn))
+		l.int64(2, int64(line))
+
+		var loc protoBuf
+		loc.uint64(1, id)
+		loc.bytes(4, l)
+		b.locs.bytes(4, loc)
+	}
+	return id
+}
+
+// protoBuf encodes the few protocol buffer wire types needed by the
+// profile.proto messages
+type protoBuf struct {
+	raw []byte
+}
+
+func (p *protoBuf) varint(x uint64) {
+	for x >= 0x80 {
+		p.raw = append(p.raw, byte(x)|0x80)
+		x >>= 7
+	}
+	p.raw = append(p.raw, byte(x))
+}
+
+func (p *protoBuf) key(field int, wireType int) {
+	p.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (p *protoBuf) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	p.key(field, 0)
+	p.varint(x)
+}
+
+func (p *protoBuf) int64(field int, x int64) {
+	p.uint64(field, uint64(x))
+}
+
+func (p *protoBuf) string(field int, s string) {
+	p.key(field, 2)
+	p.varint(uint64(len(s)))
+	p.raw = append(p.raw, s...)
+}
+
+func (p *protoBuf) bytes(field int, msg protoBuf) {
+	p.key(field, 2)
+	p.varint(uint64(len(msg.raw)))
+	p.raw = append(p.raw, msg.raw...)
+}
+
+func (p *protoBuf) packedUint64(field int, xs []uint64) {
+	var packed protoBuf
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	p.bytes(field, packed)
+}
+
+func (p *protoBuf) packedInt64(field int, xs []int64) {
+	var packed protoBuf
+	for _, x := range xs {
+		packed.varint(uint64(x))
+	}
+	p.bytes(field, packed)
+}
@@ -0,0 +1,319 @@
+package interpreter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// Profiler measures where a program spends its time. Time is charged
+// exactly, not sampled: every statement and every call entry or exit
+// is a checkpoint where the time elapsed since the previous one is
+// added to the line being executed. A Profiler can't be shared between
+// interpreters running concurrently.
+type Profiler struct {
+	now   func() time.Time
+	last  time.Time
+	start time.Time
+	total time.Duration
+
+	funcs map[funcKey]*FuncStats
+	lines map[lineKey]*LineStats
+	// calls made from the top level, the root of the call tree
+	root  *callNode
+	stack []*profFrame
+}
+
+// FuncStats are the measures of a Lox function or a native
+type FuncStats struct {
+	Name string
+	// line of the declaration, 0 for natives and the top level
+	Line  int
+	Calls int
+	// Inclusive counts the time spent in the function and the
+	// functions it called, Exclusive only the time spent in its body
+	Inclusive time.Duration
+	Exclusive time.Duration
+
+	// activations on the stack, recursive calls count once in Inclusive
+	active int
+}
+
+// LineStats are the measures of a source line, in a given function
+type LineStats struct {
+	Function string
+	Line     int
+	// number of statements executed on the line
+	Hits      int
+	Exclusive time.Duration
+}
+
+type funcKey struct {
+	name string
+	line int
+}
+
+type lineKey struct {
+	fn   *FuncStats
+	line int
+}
+
+// callNode is a distinct call stack, reached by calling fn from the
+// line callLine of the parent function
+type callNode struct {
+	fn       *FuncStats
+	callLine int
+	parent   *callNode
+	children map[lineKey]*callNode
+	calls    int
+	// time spent in fn by line, excluding callees
+	self map[int]time.Duration
+}
+
+type profFrame struct {
+	node  *callNode
+	line  int
+	start time.Time
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		now:   time.Now,
+		funcs: make(map[funcKey]*FuncStats),
+		lines: make(map[lineKey]*LineStats),
+	}
+}
+
+// WithProfiler records the execution of every statement and call in p
+func WithProfiler(p *Profiler) Option {
+	return func(i *Interpreter) {
+		i.profiler = p
+	}
+}
+
+func (p *Profiler) function(name string, line int) *FuncStats {
+	k := funcKey{name, line}
+	fn, ok := p.funcs[k]
+	if !ok {
+		fn = &FuncStats{Name: name, Line: line}
+		p.funcs[k] = fn
+	}
+	return fn
+}
+
+// checkpoint charges the time elapsed since the last checkpoint to the
+// line being executed
+func (p *Profiler) checkpoint() time.Time {
+	now := p.now()
+	if len(p.stack) == 0 {
+		p.last = now
+		return now
+	}
+
+	d := now.Sub(p.last)
+	p.last = now
+	top := p.stack[len(p.stack)-1]
+	top.node.fn.Exclusive += d
+	top.node.self[top.line] += d
+	if ls, ok := p.lines[lineKey{top.node.fn, top.line}]; ok {
+		ls.Exclusive += d
+	}
+	return now
+}
+
+func (p *Profiler) statement(s parser.Stmt) {
+	p.checkpoint()
+	top := p.stack[len(p.stack)-1]
+	top.line = s.Line()
+
+	k := lineKey{top.node.fn, top.line}
+	ls, ok := p.lines[k]
+	if !ok {
+		ls = &LineStats{Function: top.node.fn.Name, Line: top.line}
+		p.lines[k] = ls
+	}
+	ls.Hits++
+}
+
+func (p *Profiler) enter(name string, line int) {
+	now := p.checkpoint()
+	fn := p.function(name, line)
+	fn.Calls++
+	fn.active++
+
+	var node *callNode
+	if len(p.stack) == 0 {
+		if p.root == nil {
+			p.root = newCallNode(fn, 0, nil)
+			p.start = now
+		}
+		node = p.root
+	} else {
+		top := p.stack[len(p.stack)-1]
+		k := lineKey{fn, top.line}
+		node = top.node.children[k]
+		if node == nil {
+			node = newCallNode(fn, top.line, top.node)
+			top.node.children[k] = node
+		}
+	}
+	node.calls++
+	p.stack = append(p.stack, &profFrame{node: node, line: line, start: now})
+}
+
+func (p *Profiler) exit() {
+	now := p.checkpoint()
+	top := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+
+	fn := top.node.fn
+	fn.active--
+	if fn.active == 0 {
+		fn.Inclusive += now.Sub(top.start)
+	}
+	if len(p.stack) == 0 {
+		p.total += now.Sub(top.start)
+	}
+}
+
+func newCallNode(fn *FuncStats, callLine int, parent *callNode) *callNode {
+	return &callNode{
+		fn:       fn,
+		callLine: callLine,
+		parent:   parent,
+		children: make(map[lineKey]*callNode),
+		self:     make(map[int]time.Duration),
+	}
+}
+
+// callableName identifies a callable in the profile
+func callableName(c Callable) (string, int) {
+	switch c := c.(type) {
+	case *LoxFunction:
+		return c.Declaration.Name.Lexeme, c.Declaration.Name.Line
+	case *nativeFn:
+		return c.name, 0
+	case nativeClock:
+		return "clock", 0
+	}
+	return fmt.Sprint(c), 0
+}
+
+// Functions returns the measures of every function called, the most
+// expensive first
+func (p *Profiler) Functions() []FuncStats {
+	var res []FuncStats
+	for _, fn := range p.funcs {
+		res = append(res, *fn)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Exclusive != res[j].Exclusive {
+			return res[i].Exclusive > res[j].Exclusive
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// Lines returns the measures of every line executed, the most
+// expensive first
+func (p *Profiler) Lines() []LineStats {
+	var res []LineStats
+	for _, ls := range p.lines {
+		res = append(res, *ls)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Exclusive != res[j].Exclusive {
+			return res[i].Exclusive > res[j].Exclusive
+		}
+		if res[i].Function != res[j].Function {
+			return res[i].Function < res[j].Function
+		}
+		return res[i].Line < res[j].Line
+	})
+	return res
+}
+
+// WriteText writes a human readable report, functions then lines
+func (p *Profiler) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "total time: %v\t\n\n", p.total)
+
+	fmt.Fprintf(tw, "calls\tinclusive\texclusive\t \tfunction\t\n")
+	for _, fn := range p.Functions() {
+		name := fn.Name
+		if fn.Line > 0 {
+			name = fmt.Sprintf("%s (line %d)", fn.Name, fn.Line)
+		}
+		fmt.Fprintf(tw, "%d\t%v\t%v\t \t%s\t\n", fn.Calls, fn.Inclusive, fn.Exclusive, name)
+	}
+
+	fmt.Fprintf(tw, "\nhits\texclusive\t \tline\t \tfunction\t\n")
+	for _, ls := range p.Lines() {
+		fmt.Fprintf(tw, "%d\t%v\t \t%d\t \t%s\t\n", ls.Hits, ls.Exclusive, ls.Line, ls.Function)
+	}
+	return tw.Flush()
+}
+
+// WriteCollapsed writes one line per call stack, the functions
+// separated by ';' followed by the time spent in the last one in
+// nanoseconds, the input format of flame graph tools
+func (p *Profiler) WriteCollapsed(w io.Writer) error {
+	stacks := make(map[string]time.Duration)
+	p.walk(func(n *callNode) {
+		var names []string
+		for c := n; c != nil; c = c.parent {
+			names = append(names, c.fn.Name)
+		}
+		for l, r := 0, len(names)-1; l < r; l, r = l+1, r-1 {
+			names[l], names[r] = names[r], names[l]
+		}
+		key := strings.Join(names, ";")
+		for _, d := range n.self {
+			stacks[key] += d
+		}
+	})
+
+	var keys []string
+	for k := range stacks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%s %d\n", k, stacks[k].Nanoseconds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk calls f on every node of the call tree, parents first
+func (p *Profiler) walk(f func(*callNode)) {
+	if p.root == nil {
+		return
+	}
+	var visit func(n *callNode)
+	visit = func(n *callNode) {
+		f(n)
+
+		children := make([]*callNode, 0, len(n.children))
+		for _, c := range n.children {
+			children = append(children, c)
+		}
+		sort.Slice(children, func(i, j int) bool {
+			if children[i].fn.Name != children[j].fn.Name {
+				return children[i].fn.Name < children[j].fn.Name
+			}
+			return children[i].callLine < children[j].callLine
+		})
+		for _, c := range children {
+			visit(c)
+		}
+	}
+	visit(p.root)
+}
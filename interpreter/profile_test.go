@@ -0,0 +1,141 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+const profileSrc = `fun fib(n) {
+  if (n <= 1) return n;
+  return fib(n - 2) + fib(n - 1);
+}
+fun run() {
+  var r = fib(4);
+  return sqrt(r);
+}
+print run();
+`
+
+func profile(t *testing.T) *interpreter.Profiler {
+	p := interpreter.NewProfiler()
+	interpreter.SetClock(p, time.Millisecond)
+
+	var out bytes.Buffer
+	interp := interpreter.New(interpreter.WithOutput(&out), interpreter.WithProfiler(p))
+	if err := interp.Exec(profileSrc); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1.7320508075688772\n" {
+		t.Errorf("profiling changed the output: %q", out.String())
+	}
+	return p
+}
+
+func TestProfilerFunctions(t *testing.T) {
+	p := profile(t)
+
+	stats := make(map[string]interpreter.FuncStats)
+	var exclusive time.Duration
+	for _, fn := range p.Functions() {
+		stats[fn.Name] = fn
+		exclusive += fn.Exclusive
+	}
+
+	calls := map[string]int{"<script>": 1, "run": 1, "fib": 9, "sqrt": 1}
+	for name, n := range calls {
+		if stats[name].Calls != n {
+			t.Errorf("%s: expected %d calls, got %d", name, n, stats[name].Calls)
+		}
+	}
+	if len(stats) != len(calls) {
+		t.Errorf("unexpected functions: %v", stats)
+	}
+
+	script := stats["<script>"]
+	if script.Inclusive != exclusive {
+		t.Errorf("the exclusive times %v should add up to the total %v", exclusive, script.Inclusive)
+	}
+	// recursive calls must not be counted several times
+	if fib := stats["fib"]; fib.Inclusive > stats["run"].Inclusive || fib.Inclusive < fib.Exclusive {
+		t.Errorf("inconsistent fib times: %+v", fib)
+	}
+	if stats["fib"].Line != 1 || stats["sqrt"].Line != 0 {
+		t.Errorf("unexpected declaration lines: %+v", stats)
+	}
+}
+
+func TestProfilerLines(t *testing.T) {
+	p := profile(t)
+
+	hits := make(map[string]int)
+	for _, ls := range p.Lines() {
+		hits[fmt.Sprintf("%s:%d", ls.Function, ls.Line)] = ls.Hits
+	}
+	expected := map[string]int{
+		"<script>:1": 1, "<script>:5": 1, "<script>:9": 1,
+		// the if and the return of the base case share the line
+		"fib:2": 14, "fib:3": 4,
+		"run:6": 1, "run:7": 1,
+	}
+	for k, n := range expected {
+		if hits[k] != n {
+			t.Errorf("%s: expected %d hits, got %d", k, n, hits[k])
+		}
+	}
+}
+
+func TestProfilerOutputs(t *testing.T) {
+	p := profile(t)
+
+	var text bytes.Buffer
+	if err := p.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "fib (line 1)") {
+		t.Errorf("unexpected text report:\n%s", text.String())
+	}
+
+	var collapsed bytes.Buffer
+	if err := p.WriteCollapsed(&collapsed); err != nil {
+		t.Fatal(err)
+	}
+	for _, stack := range []string{"<script>;run;fib;fib;fib ", "<script>;run;sqrt "} {
+		if !strings.Contains(collapsed.String(), stack) {
+			t.Errorf("missing stack %q in:\n%s", stack, collapsed.String())
+		}
+	}
+
+	var pprof bytes.Buffer
+	if err := p.WritePprof(&pprof); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(&pprof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"calls", "nanoseconds", "fib", "sqrt"} {
+		if !bytes.Contains(raw, []byte(s)) {
+			t.Errorf("missing string %q in the pprof profile", s)
+		}
+	}
+}
+
+func BenchmarkFibProfiled(b *testing.B) {
+	for n := 0; n < b.N; n++ {
+		p := interpreter.NewProfiler()
+		if err := interpreter.New(interpreter.WithProfiler(p)).Exec(fibSrc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package interpreter
+
+import (
+	"context"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// Program is a script scanned, parsed and resolved once, ready to be
+// executed any number of times. Executing a program never modifies it,
+// so the same Program can be run by several interpreters at once, each
+// in its own goroutine. An Interpreter itself is not safe for
+// concurrent use.
+type Program struct {
+	source string
+	stmts  []parser.Stmt
+}
+
+// Compile prepares src to be executed with ExecProgram, the options of
+// i affecting compilation, like WithOptimizer, are applied
+func (i *Interpreter) Compile(src string) (*Program, error) {
+	stmts, err := i.parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{source: src, stmts: stmts}, nil
+}
+
+// ExecProgram executes p in the interpreter globals. Globals defined
+// by the host before, or by previous programs, are visible to p; use a
+// new Interpreter to run it in a fresh environment.
+func (i *Interpreter) ExecProgram(p *Program) error {
+	if i.coverage != nil {
+		i.coverage.register(i.coverFile, p.source, p.stmts)
+	}
+	if i.profiler != nil {
+		i.profiler.enter("<script>", 0)
+		defer i.profiler.exit()
+	}
+	_, err := i.execute(p.stmts, i.globals)
+	return err
+}
+
+// ExecProgramContext is like ExecProgram, natives called by p receive
+// ctx
+func (i *Interpreter) ExecProgramContext(ctx context.Context, p *Program) error {
+	prev := i.ctx
+	i.ctx = ctx
+	defer func() { i.ctx = prev }()
+	return i.ExecProgram(p)
+}
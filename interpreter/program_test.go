@@ -0,0 +1,103 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+const programSrc = `
+fun fact(n) {
+  if (n <= 1) return 1;
+  return n * fact(n - 1);
+}
+fun sum(n, acc) {
+  if (n == 0) return acc;
+  return sum(n - 1, acc + input);
+}
+var label = "result";
+try {
+  if (input > 5) throw "too big";
+  print label + ": " + str(fact(input) + sum(10, 0));
+} catch (e) {
+  print e;
+}
+`
+
+// the same program runs in many interpreters at once, run with -race
+func TestProgramConcurrent(t *testing.T) {
+	prog, err := interpreter.New(interpreter.WithOptimizer()).Compile(programSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fact := func(n int) int {
+		r := 1
+		for ; n > 1; n-- {
+			r *= n
+		}
+		return r
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				input := (g + n) % 7
+
+				var out bytes.Buffer
+				interp := interpreter.New(interpreter.WithOutput(&out))
+				interp.Globals().Define("input", interpreter.ValueOf(input))
+				if err := interp.ExecProgram(prog); err != nil {
+					errs <- err
+					return
+				}
+
+				expected := fmt.Sprintf("result: %d", fact(input)+10*input)
+				if input > 5 {
+					expected = "too big"
+				}
+				if got := strings.TrimSpace(out.String()); got != expected {
+					errs <- fmt.Errorf("input %d: got %q, expected %q", input, got, expected)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestProgramReuse(t *testing.T) {
+	var out bytes.Buffer
+	interp := interpreter.New(interpreter.WithOutput(&out))
+	if err := interp.Exec("var runs = 0;"); err != nil {
+		t.Fatal(err)
+	}
+	prog, err := interp.Compile("runs = runs + 1; print runs;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 3; n++ {
+		if err := interp.ExecProgram(prog); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out.String() != "1\n2\n3\n" {
+		t.Errorf("got %q", out.String())
+	}
+
+	if _, err := interp.Compile("print (1;"); err == nil {
+		t.Error("expected a syntax error")
+	}
+}
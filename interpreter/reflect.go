@@ -0,0 +1,352 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+var (
+	valueType   = reflect.TypeOf(Value{})
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// Reflect exposes the Go value v to Lox. The exported fields of a
+// struct, or the entries of a map with string keys, are its properties
+// and its exported methods can be called. Fields can only be assigned
+// through a pointer to the struct.
+//
+// Values are converted both ways: numbers to and from any integer or
+// float type they fit in, lists to and from slices, and nested
+// structs, maps and functions are reflected as well. A non-nil error
+// returned last by a method becomes a runtime error. A method taking a
+// context.Context first receives the one of the call, see CallContext.
+func Reflect(v interface{}) Value {
+	return toLox(reflect.ValueOf(v))
+}
+
+// goObject is a struct, a pointer to a struct or a map seen from Lox
+type goObject struct {
+	v reflect.Value
+}
+
+func (o *goObject) Get(name string) (Value, error) {
+	if m := o.v.MethodByName(name); m.IsValid() {
+		return Object(&goFunc{name: name, fn: m}), nil
+	}
+	v := reflect.Indirect(o.v)
+	if v.Kind() == reflect.Map {
+		// a missing key gives nil
+		return toLox(v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))), nil
+	}
+	f, err := field(v, name)
+	if err != nil {
+		return Value{}, err
+	}
+	return toLox(f), nil
+}
+
+func (o *goObject) Set(name string, value Value) error {
+	v := reflect.Indirect(o.v)
+	if v.Kind() == reflect.Map {
+		x, m := fromLox(value, v.Type().Elem())
+		if m != nil {
+			return propertyError(name, m)
+		}
+		v.SetMapIndex(reflect.ValueOf(name).Convert(v.Type().Key()), x)
+		return nil
+	}
+
+	f, err := field(v, name)
+	if err != nil {
+		return err
+	}
+	if !f.CanSet() {
+		return &RuntimeError{Msg: fmt.Sprintf("cannot set %s, %s is not passed by pointer", name, v.Type())}
+	}
+	x, m := fromLox(value, f.Type())
+	if m != nil {
+		return propertyError(name, m)
+	}
+	f.Set(x)
+	return nil
+}
+
+func (o *goObject) String() string {
+	return fmt.Sprintf("<go %s>", o.v.Type())
+}
+
+// field returns the exported field name of the struct v
+func field(v reflect.Value, name string) (reflect.Value, error) {
+	sf, ok := v.Type().FieldByName(name)
+	if !ok || !sf.IsExported() {
+		return reflect.Value{}, &RuntimeError{Msg: fmt.Sprintf("%s has no property %s", v.Type(), name)}
+	}
+	// promoted through a nil embedded pointer
+	f, err := v.FieldByIndexErr(sf.Index)
+	if err != nil {
+		return reflect.Value{}, &RuntimeError{Msg: fmt.Sprintf("cannot access %s: %v", name, err)}
+	}
+	// or through an unexported embedded struct
+	if !f.CanInterface() {
+		return reflect.Value{}, &RuntimeError{Msg: fmt.Sprintf("%s has no property %s", v.Type(), name)}
+	}
+	return f, nil
+}
+
+func propertyError(name string, m *mismatch) error {
+	return &RuntimeError{Msg: m.describe("property " + name)}
+}
+
+// goFunc is a Go function or method called from Lox, a variadic one
+// takes its last arguments as a list
+type goFunc struct {
+	name string
+	fn   reflect.Value
+}
+
+// takesContext tells whether the first parameter of f is a context
+func (f *goFunc) takesContext() bool {
+	t := f.fn.Type()
+	return t.NumIn() > 0 && t.In(0) == contextType
+}
+
+func (f *goFunc) Arity() int {
+	if f.takesContext() {
+		return f.fn.Type().NumIn() - 1
+	}
+	return f.fn.Type().NumIn()
+}
+
+func (f *goFunc) Call(c CallContext, args []Value) (Value, error) {
+	t := f.fn.Type()
+	var in []reflect.Value
+	if f.takesContext() {
+		in = append(in, reflect.ValueOf(c.Context()))
+	}
+	for i, a := range args {
+		x, m := fromLox(a, t.In(len(in)))
+		if m != nil {
+			return Value{}, &RuntimeError{Msg: f.name + ": " + m.describe(fmt.Sprintf("argument %d", i+1))}
+		}
+		in = append(in, x)
+	}
+
+	var out []reflect.Value
+	if t.IsVariadic() {
+		out = f.fn.CallSlice(in)
+	} else {
+		out = f.fn.Call(in)
+	}
+	if n := len(out); n > 0 && t.Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return Value{}, &RuntimeError{Msg: f.name + ": " + err.Error()}
+		}
+		out = out[:n-1]
+	}
+
+	switch len(out) {
+	case 0:
+		return Value{}, nil
+	case 1:
+		return toLox(out[0]), nil
+	}
+	// several results are returned as a list
+	lst := &List{}
+	for _, o := range out {
+		lst.Elems = append(lst.Elems, toLox(o))
+	}
+	return Object(lst), nil
+}
+
+func (f *goFunc) String() string {
+	return "<go fn " + f.name + ">"
+}
+
+// toLox converts a Go value to a Value, see Reflect
+func toLox(v reflect.Value) Value {
+	if !v.IsValid() {
+		return Value{}
+	}
+	if v.Type() == valueType {
+		return v.Interface().(Value)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return Bool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Number(float64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Number(float64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return Number(v.Float())
+	case reflect.String:
+		return String(v.String())
+	case reflect.Interface:
+		return toLox(v.Elem())
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func:
+		if v.IsNil() {
+			return Value{}
+		}
+	}
+
+	// values already meant for Lox
+	switch x := v.Interface().(type) {
+	case Callable, Instance, *List:
+		return Object(x)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.Elem().Kind() == reflect.Struct {
+			return Object(&goObject{v: v})
+		}
+	case reflect.Struct:
+		// a field of a struct reached through a pointer can be assigned
+		if v.CanAddr() {
+			v = v.Addr()
+		}
+		return Object(&goObject{v: v})
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			return Object(&goObject{v: v})
+		}
+	case reflect.Slice, reflect.Array:
+		lst := &List{Elems: make([]Value, v.Len())}
+		for i := range lst.Elems {
+			lst.Elems[i] = toLox(v.Index(i))
+		}
+		return Object(lst)
+	case reflect.Func:
+		return Object(&goFunc{name: v.Type().String(), fn: v})
+	}
+	return Object(v.Interface())
+}
+
+// fromLox converts v to a Go value of type t, it returns why when v
+// doesn't fit
+func fromLox(v Value, t reflect.Type) (reflect.Value, *mismatch) {
+	if t == valueType {
+		return reflect.ValueOf(v), nil
+	}
+
+	x := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.kind != BoolKind {
+			return x, &mismatch{expected: t, got: v}
+		}
+		x.SetBool(v.AsBool())
+		return x, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.num
+		if v.kind != NumberKind || n != math.Trunc(n) || math.Abs(n) >= 1<<63 || x.OverflowInt(int64(n)) {
+			return x, &mismatch{expected: t, got: v}
+		}
+		x.SetInt(int64(n))
+		return x, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := v.num
+		if v.kind != NumberKind || n != math.Trunc(n) || n < 0 || n >= 1<<64 || x.OverflowUint(uint64(n)) {
+			return x, &mismatch{expected: t, got: v}
+		}
+		x.SetUint(uint64(n))
+		return x, nil
+	case reflect.Float32, reflect.Float64:
+		if v.kind != NumberKind {
+			return x, &mismatch{expected: t, got: v}
+		}
+		x.SetFloat(v.num)
+		return x, nil
+	case reflect.String:
+		if v.kind != StringKind {
+			return x, &mismatch{expected: t, got: v}
+		}
+		x.SetString(v.AsString())
+		return x, nil
+	case reflect.Slice:
+		if lst, ok := v.obj.(*List); ok {
+			x = reflect.MakeSlice(t, len(lst.Elems), len(lst.Elems))
+			for i, e := range lst.Elems {
+				elem, m := fromLox(e, t.Elem())
+				if m != nil {
+					m.elems = append([]int{i}, m.elems...)
+					return x, m
+				}
+				x.Index(i).Set(elem)
+			}
+			return x, nil
+		}
+	}
+
+	g := goValue(v)
+	if g == nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Interface, reflect.Chan:
+			return x, nil
+		}
+		return x, &mismatch{expected: t, got: v}
+	}
+	rg := reflect.ValueOf(g)
+	if rg.Type().AssignableTo(t) {
+		return rg, nil
+	}
+	// a struct expected by value
+	if rg.Kind() == reflect.Ptr && rg.Elem().Type().AssignableTo(t) {
+		return rg.Elem(), nil
+	}
+	return x, &mismatch{expected: t, got: v}
+}
+
+// mismatch tells why fromLox couldn't convert a value
+type mismatch struct {
+	expected reflect.Type
+	got      Value
+	// index of the list element that didn't fit, outermost first
+	elems []int
+}
+
+// describe explains the mismatch for the argument or property subject
+func (m *mismatch) describe(subject string) string {
+	for _, i := range m.elems {
+		subject += fmt.Sprintf(", element %d", i)
+	}
+	return fmt.Sprintf("%s must be %s, got %s", subject, describe(m.expected), typeName(m.got))
+}
+
+// goValue returns the Go value held by v, unwrapping reflected values
+func goValue(v Value) interface{} {
+	switch o := v.obj.(type) {
+	case *goObject:
+		return o.v.Interface()
+	case *goFunc:
+		return o.fn.Interface()
+	}
+	return v.Interface()
+}
+
+// describe names the values of type t in error messages
+func describe(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a bool"
+	case reflect.Int:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		elem := describe(t.Elem())
+		return "a list of " + elem[strings.Index(elem, " ")+1:]
+	}
+	switch t.String()[0] {
+	case 'a', 'e', 'i', 'o':
+		return "an " + t.String()
+	}
+	return "a " + t.String()
+}
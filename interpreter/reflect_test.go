@@ -0,0 +1,90 @@
+package interpreter_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+type address struct {
+	City string
+}
+
+type user struct {
+	Name    string
+	Age     uint8
+	Tags    []string
+	Home    address
+	private int
+}
+
+func (u *user) Greet(greeting string) string {
+	return greeting + " " + u.Name
+}
+
+func (u *user) Birthday() (int, error) {
+	if u.Age == 255 {
+		return 0, errors.New("too old")
+	}
+	u.Age++
+	return int(u.Age), nil
+}
+
+func (u *user) Tag(tags ...string) int {
+	u.Tags = append(u.Tags, tags...)
+	return len(u.Tags)
+}
+
+func TestReflect(t *testing.T) {
+	u := &user{Name: "Ada", Age: 36}
+	settings := map[string]interface{}{"theme": "dark"}
+
+	interp := interpreter.New()
+	interp.Globals().Define("u", interpreter.Reflect(u))
+	interp.Globals().Define("settings", interpreter.Reflect(settings))
+	interp.Globals().Define("byValue", interpreter.Reflect(user{Name: "Bob"}))
+
+	got := run(t, interp, `
+		print u.Name + " " + str(u.Age);
+		print u.Greet("hello");
+		print u.Birthday();
+		u.Home.City = "London";
+		u.Name = "Ada L.";
+		print u.Tag(split("a,b", ","));
+		print u.Tags;
+		print settings.theme;
+		print settings.missing;
+		settings.size = 12;
+		print byValue.Name;
+		print u;
+	`)
+	expected := "Ada 36\nhello Ada\n37\n2\n[a, b]\ndark\nnil\nBob\n<go *interpreter_test.user>\n"
+	if got != expected {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+	if u.Name != "Ada L." || u.Age != 37 || u.Home.City != "London" || len(u.Tags) != 2 {
+		t.Errorf("user not updated: %+v", u)
+	}
+	if settings["size"] != 12.0 {
+		t.Errorf("settings not updated: %v", settings)
+	}
+
+	for src, msg := range map[string]string{
+		`u.Age = 256;`:               "property Age must be a uint8, got number",
+		`u.Age = "old";`:             "property Age must be a uint8, got string",
+		`u.Nope;`:                    "interpreter_test.user has no property Nope",
+		`u.private;`:                 "interpreter_test.user has no property private",
+		`byValue.Name = "Carl";`:     "cannot set Name, interpreter_test.user is not passed by pointer",
+		`u.Greet(1);`:                "Greet: argument 1 must be a string, got number",
+		`u.Greet();`:                 "expected 1 arguments but got 0",
+		`u.Age = 255; u.Birthday();`: "Birthday: too old",
+		`(1).x = 2;`:                 "only Go values have settable properties, got number",
+	} {
+		err := interp.Exec(src)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: got error %v, expected %s", src, err, msg)
+		}
+	}
+}
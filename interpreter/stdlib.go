@@ -0,0 +1,276 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// nativeFn is a builtin function implemented in Go, it checks its
+// own arguments and reports type errors as RuntimeError
+type nativeFn struct {
+	name  string
+	arity int
+	fn    func(args []Value) (Value, error)
+}
+
+func (n *nativeFn) Call(c CallContext, args []Value) (Value, error) {
+	return n.fn(args)
+}
+func (n *nativeFn) Arity() int {
+	return n.arity
+}
+
+func (n *nativeFn) String() string {
+	return "<nativeFn " + n.name + ">"
+}
+
+// List is the value returned by natives producing several values, like split
+type List struct {
+	Elems []Value
+}
+
+func (l *List) String() string {
+	var elems []string
+	for _, e := range l.Elems {
+		elems = append(elems, Stringify(e))
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+func (i *Interpreter) defineStdlib(env *Env) {
+	natives := []*nativeFn{
+		// math
+		{"sqrt", 1, mathFn("sqrt", math.Sqrt)},
+		{"floor", 1, mathFn("floor", math.Floor)},
+		{"abs", 1, mathFn("abs", math.Abs)},
+		{"pow", 2, func(args []Value) (Value, error) {
+			x, y, err := twoNumbers("pow", args)
+			if err != nil {
+				return Value{}, err
+			}
+			return Number(math.Pow(x, y)), nil
+		}},
+		{"min", 2, func(args []Value) (Value, error) {
+			x, y, err := twoNumbers("min", args)
+			if err != nil {
+				return Value{}, err
+			}
+			return Number(math.Min(x, y)), nil
+		}},
+		{"max", 2, func(args []Value) (Value, error) {
+			x, y, err := twoNumbers("max", args)
+			if err != nil {
+				return Value{}, err
+			}
+			return Number(math.Max(x, y)), nil
+		}},
+		{"random", 0, func(args []Value) (Value, error) {
+			return Number(i.rand.Float64()), nil
+		}},
+
+		// strings
+		{"len", 1, nativeLen},
+		{"substr", 3, nativeSubstr},
+		{"indexOf", 2, func(args []Value) (Value, error) {
+			s, sub, err := twoStrings("indexOf", args)
+			if err != nil {
+				return Value{}, err
+			}
+			idx := strings.Index(s, sub)
+			if idx < 0 {
+				return Number(-1), nil
+			}
+			return Number(float64(len([]rune(s[:idx])))), nil
+		}},
+		{"split", 2, func(args []Value) (Value, error) {
+			s, sep, err := twoStrings("split", args)
+			if err != nil {
+				return Value{}, err
+			}
+			lst := &List{}
+			for _, part := range strings.Split(s, sep) {
+				lst.Elems = append(lst.Elems, String(part))
+			}
+			return Object(lst), nil
+		}},
+		{"upper", 1, stringFn("upper", strings.ToUpper)},
+		{"lower", 1, stringFn("lower", strings.ToLower)},
+		{"trim", 1, stringFn("trim", strings.TrimSpace)},
+		{"replace", 3, func(args []Value) (Value, error) {
+			s, err := argString("replace", args, 0)
+			if err != nil {
+				return Value{}, err
+			}
+			old, err := argString("replace", args, 1)
+			if err != nil {
+				return Value{}, err
+			}
+			repl, err := argString("replace", args, 2)
+			if err != nil {
+				return Value{}, err
+			}
+			return String(strings.ReplaceAll(s, old, repl)), nil
+		}},
+
+		// conversions
+		{"str", 1, func(args []Value) (Value, error) {
+			return String(Stringify(args[0])), nil
+		}},
+		{"num", 1, nativeNum},
+		{"type", 1, func(args []Value) (Value, error) {
+			return String(typeName(args[0])), nil
+		}},
+	}
+
+	for _, n := range natives {
+		env.Define(n.name, Object(n))
+	}
+}
+
+func nativeLen(args []Value) (Value, error) {
+	if args[0].kind == StringKind {
+		return Number(float64(len([]rune(args[0].AsString())))), nil
+	}
+	if lst, ok := args[0].obj.(*List); ok {
+		return Number(float64(len(lst.Elems))), nil
+	}
+	return Value{}, argError("len", 0, "a string or a list", args[0])
+}
+
+func nativeSubstr(args []Value) (Value, error) {
+	s, err := argString("substr", args, 0)
+	if err != nil {
+		return Value{}, err
+	}
+	start, err := argInt("substr", args, 1)
+	if err != nil {
+		return Value{}, err
+	}
+	end, err := argInt("substr", args, 2)
+	if err != nil {
+		return Value{}, err
+	}
+
+	runes := []rune(s)
+	if start < 0 || end > len(runes) || start > end {
+		return Value{}, &RuntimeError{
+			Msg: fmt.Sprintf("substr: invalid range [%d, %d) for string of length %d", start, end, len(runes)),
+		}
+	}
+	return String(string(runes[start:end])), nil
+}
+
+func nativeNum(args []Value) (Value, error) {
+	switch v := args[0]; v.kind {
+	case NumberKind:
+		return v, nil
+	case BoolKind:
+		// booleans are stored as 0 or 1
+		return Number(v.num), nil
+	case StringKind:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v.AsString()), 64)
+		if err != nil {
+			return Value{}, &RuntimeError{
+				Msg: fmt.Sprintf("num: cannot convert %q to a number", v.AsString()),
+			}
+		}
+		return Number(n), nil
+	}
+	return Value{}, argError("num", 0, "a number, a string or a bool", args[0])
+}
+
+func mathFn(name string, f func(float64) float64) func([]Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		x, err := argNumber(name, args, 0)
+		if err != nil {
+			return Value{}, err
+		}
+		return Number(f(x)), nil
+	}
+}
+
+func stringFn(name string, f func(string) string) func([]Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		s, err := argString(name, args, 0)
+		if err != nil {
+			return Value{}, err
+		}
+		return String(f(s)), nil
+	}
+}
+
+func twoNumbers(name string, args []Value) (float64, float64, error) {
+	x, err := argNumber(name, args, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	y, err := argNumber(name, args, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return x, y, nil
+}
+
+func twoStrings(name string, args []Value) (string, string, error) {
+	x, err := argString(name, args, 0)
+	if err != nil {
+		return "", "", err
+	}
+	y, err := argString(name, args, 1)
+	if err != nil {
+		return "", "", err
+	}
+	return x, y, nil
+}
+
+func argNumber(name string, args []Value, i int) (float64, error) {
+	if args[i].kind == NumberKind {
+		return args[i].num, nil
+	}
+	return 0, argError(name, i, "a number", args[i])
+}
+
+func argInt(name string, args []Value, i int) (int, error) {
+	n, err := argNumber(name, args, i)
+	if err != nil {
+		return 0, err
+	}
+	if n != math.Trunc(n) || math.IsInf(n, 0) {
+		return 0, argError(name, i, "an integer", args[i])
+	}
+	return int(n), nil
+}
+
+func argString(name string, args []Value, i int) (string, error) {
+	if args[i].kind == StringKind {
+		return args[i].AsString(), nil
+	}
+	return "", argError(name, i, "a string", args[i])
+}
+
+func argError(name string, i int, expected string, got Value) error {
+	return &RuntimeError{
+		Msg: fmt.Sprintf("%s: argument %d must be %s, got %s", name, i+1, expected, typeName(got)),
+	}
+}
+
+func typeName(v Value) string {
+	if v.kind != ObjectKind {
+		return v.kind.String()
+	}
+	switch o := v.obj.(type) {
+	case *List:
+		return "list"
+	case Callable:
+		return "function"
+	case *goObject:
+		return o.v.Type().String()
+	case *Module:
+		return "module"
+	case *ErrorValue:
+		return "error"
+	}
+	return "object"
+}
@@ -0,0 +1,98 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+)
+
+// run executes src in the globals of i and returns the first error
+func run(i *Interpreter, src string) error {
+	return i.Exec(src)
+}
+
+// global returns the global variable name of i, formatted like print
+func global(t *testing.T, i *Interpreter, name string) string {
+	t.Helper()
+	v, err := i.env.Get(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Stringify(v)
+}
+
+func TestStdlib(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		want string
+	}{
+		{`sqrt(16)`, "4"},
+		{`floor(-1.5)`, "-2"},
+		{`abs(-3)`, "3"},
+		{`pow(2, 10)`, "1024"},
+		{`min(1, 2)`, "1"},
+		{`max(1, 2)`, "2"},
+		{`random() >= 0 and random() < 1`, "true"},
+
+		{`len("héllo")`, "5"},
+		{`substr("héllo", 1, 3)`, "él"},
+		{`indexOf("héllo", "l")`, "2"},
+		{`indexOf("hello", "z")`, "-1"},
+		{`split("a,b,c", ",")`, "[a, b, c]"},
+		{`len(split("a,b", ","))`, "2"},
+		{`upper("abc")`, "ABC"},
+		{`lower("ABC")`, "abc"},
+		{`trim("  a b ")`, "a b"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+
+		{`str(1.5) + "!"`, "1.5!"},
+		{`str(nil)`, "nil"},
+		{`num(" 42 ")`, "42"},
+		{`num(true)`, "1"},
+		{`num(false)`, "0"},
+		{`type(1)`, "number"},
+		{`type("a")`, "string"},
+		{`type(nil)`, "nil"},
+		{`type(true)`, "bool"},
+		{`type(split("a", ","))`, "list"},
+		{`type(sqrt)`, "function"},
+	} {
+		i := New()
+		if err := run(i, "var result = "+tc.expr+";"); err != nil {
+			t.Errorf("%s: %v", tc.expr, err)
+			continue
+		}
+		if got := global(t, i, "result"); got != tc.want {
+			t.Errorf("%s: got %q, expected %q", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestStdlibErrors(t *testing.T) {
+	for _, tc := range []struct {
+		expr string
+		err  string
+	}{
+		{`sqrt("4")`, "sqrt: argument 1 must be a number, got string"},
+		{`pow(2, nil)`, "pow: argument 2 must be a number, got nil"},
+		{`len(1)`, "len: argument 1 must be a string or a list, got number"},
+		{`upper(true)`, "upper: argument 1 must be a string, got bool"},
+		{`substr("abc", 0.5, 1)`, "substr: argument 2 must be an integer, got number"},
+		{`substr("abc", 2, 1)`, "substr: invalid range [2, 1) for string of length 3"},
+		{`substr("abc", 0, 4)`, "substr: invalid range [0, 4) for string of length 3"},
+		{`num("x")`, `num: cannot convert "x" to a number`},
+		{`num(nil)`, "num: argument 1 must be a number, a string or a bool, got nil"},
+		{`replace("a", 1, "b")`, "replace: argument 2 must be a string, got number"},
+		{`replace("a", "b", nil)`, "replace: argument 3 must be a string, got nil"},
+
+		// operators name the Lox types too
+		{`1 + nil`, "unimplemented operation number + nil"},
+		{`-"a"`, "operand of - must be a number, got string"},
+		{`sqrt == 1`, "cannot compare function and number"},
+		{`split("a", ",").x`, "have properties, got list"},
+	} {
+		err := run(New(), tc.expr+";")
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got error %v, expected %q", tc.expr, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package interpreter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// JSONTracer is a Hooks writing one JSON object per line for each hook
+// call, with the fields:
+//
+//	seq       position of the event in the trace
+//	time      unix time in nanoseconds
+//	event     statement, call, return, assign or error
+//	depth     number of calls in progress
+//	line      line of the statement, of the call or of the error
+//	kind      type of statement, or of error: runtime or thrown
+//	function  name of the function called or returning
+//	args      arguments of a call
+//	result    value returned by a call
+//	name      variable assigned
+//	value     value assigned
+//	error     error message of a call or of an error event
+//
+// Values which are not numbers, strings, booleans or nil are written
+// the way print displays them.
+type JSONTracer struct {
+	mu    sync.Mutex
+	enc   *json.Encoder
+	seq   int
+	depth int
+	err   error
+}
+
+func NewJSONTracer(w io.Writer) *JSONTracer {
+	return &JSONTracer{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error met writing the trace
+func (t *JSONTracer) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func (t *JSONTracer) write(event string, fields map[string]interface{}) {
+	t.seq++
+	fields["seq"] = t.seq
+	fields["time"] = time.Now().UnixNano()
+	fields["event"] = event
+	fields["depth"] = t.depth
+	if err := t.enc.Encode(fields); err != nil && t.err == nil {
+		t.err = err
+	}
+}
+
+func (t *JSONTracer) Statement(s parser.Stmt) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.write("statement", map[string]interface{}{
+		"line": s.Line(),
+		"kind": strings.TrimPrefix(fmt.Sprintf("%T", s), "*parser."),
+	})
+}
+
+func (t *JSONTracer) CallEnter(fn Callable, args []Value, line int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	vals := make([]interface{}, len(args))
+	for i, a := range args {
+		vals[i] = jsonValue(a)
+	}
+	name, _ := callableName(fn)
+	t.write("call", map[string]interface{}{
+		"line":     line,
+		"function": name,
+		"args":     vals,
+	})
+	t.depth++
+}
+
+func (t *JSONTracer) CallExit(fn Callable, result Value, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.depth--
+	name, _ := callableName(fn)
+	fields := map[string]interface{}{
+		"function": name,
+		"result":   jsonValue(result),
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	t.write("return", fields)
+}
+
+func (t *JSONTracer) Assign(name string, value Value) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.write("assign", map[string]interface{}{
+		"name":  name,
+		"value": jsonValue(value),
+	})
+}
+
+func (t *JSONTracer) Error(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fields := map[string]interface{}{"error": err.Error()}
+	switch err := err.(type) {
+	case *RuntimeError:
+		fields["kind"] = "runtime"
+		fields["line"] = err.Line
+	case *Thrown:
+		fields["kind"] = "thrown"
+		fields["line"] = err.Line
+	}
+	t.write("error", fields)
+}
+
+// jsonValue converts a Lox value to a value encoding/json accepts
+func jsonValue(v Value) interface{} {
+	switch v.kind {
+	case NilKind, StringKind, BoolKind:
+		return v.Interface()
+	case NumberKind:
+		if math.IsNaN(v.num) || math.IsInf(v.num, 0) {
+			return Stringify(v)
+		}
+		return v.num
+	}
+	return Stringify(v)
+}
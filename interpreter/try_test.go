@@ -0,0 +1,97 @@
+package interpreter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTryCatch(t *testing.T) {
+	interp := New()
+	err := run(interp, `
+		var thrown;
+		try {
+			throw "boom";
+		} catch (e) {
+			thrown = e;
+		}
+
+		var message;
+		var line;
+		try {
+			1 + nil;
+		} catch (e) {
+			message = e.message;
+			line = e.line;
+		}
+
+		var steps = "";
+		try {
+			try {
+				steps = steps + "body ";
+				throw 1;
+			} finally {
+				steps = steps + "finally ";
+			}
+		} catch (e) {
+			steps = steps + "outer ";
+			try {
+				throw e + 1;
+			} catch (e) {
+				steps = steps + str(e);
+			}
+		}
+
+		fun pending() {
+			try {
+				return "body";
+			} catch (e) {
+				return "catch";
+			} finally {
+				steps = steps + " finally";
+			}
+		}
+		var returned = pending();
+
+		fun replaced() {
+			try {
+				throw "body";
+			} finally {
+				return "finally";
+			}
+		}
+		var replacing = replaced();
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"thrown":    "boom",
+		"message":   "unimplemented operation number + nil",
+		"line":      "12",
+		"steps":     "body finally outer 2 finally",
+		"returned":  "body",
+		"replacing": "finally",
+	} {
+		if got := global(t, interp, name); got != want {
+			t.Errorf("%s: got %q, expected %q", name, got, want)
+		}
+	}
+}
+
+func TestTryErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src string
+		err string
+	}{
+		{`throw "boom";`, "uncaught exception: boom, line 1"},
+		{`try { 1; }`, "missing catch or finally after try block"},
+		{`try { 1; } catch e {}`, "missing '(' after catch"},
+		{`try { 1 + nil; } catch (e) { e.nope; }`, "error has no property nope"},
+		{`try { throw 1; } catch (e) { throw "again"; }`, "uncaught exception: again, line 1"},
+	} {
+		err := run(New(), tc.src)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: got error %v, expected %q", tc.src, err, tc.err)
+		}
+	}
+}
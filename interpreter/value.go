@@ -0,0 +1,199 @@
+package interpreter
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Kind is the type of a Value
+type Kind uint8
+
+const (
+	NilKind Kind = iota
+	BoolKind
+	NumberKind
+	StringKind
+	// functions, lists, modules, errors and any other Go value
+	ObjectKind
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NilKind:
+		return "nil"
+	case BoolKind:
+		return "bool"
+	case NumberKind:
+		return "number"
+	case StringKind:
+		return "string"
+	}
+	return "object"
+}
+
+// Value is a Lox value. Booleans and numbers are stored unboxed so
+// evaluating an arithmetic expression doesn't allocate, strings share
+// the interface with objects to keep values small. The zero Value is
+// nil.
+type Value struct {
+	kind Kind
+	// numbers, and booleans as 0 or 1
+	num float64
+	// strings and objects
+	obj interface{}
+}
+
+func Bool(b bool) Value {
+	if b {
+		return Value{kind: BoolKind, num: 1}
+	}
+	return Value{kind: BoolKind}
+}
+
+func Number(n float64) Value {
+	return Value{kind: NumberKind, num: n}
+}
+
+func String(s string) Value {
+	return Value{kind: StringKind, obj: s}
+}
+
+// Object wraps any other Go value, a nil o gives the nil Value
+func Object(o interface{}) Value {
+	if o == nil {
+		return Value{}
+	}
+	return Value{kind: ObjectKind, obj: o}
+}
+
+// ValueOf converts a Go value to a Value: nil, bools, strings and all
+// the integer and float types get their Lox counterpart, a Value is
+// returned as is and anything else becomes an object.
+func ValueOf(v interface{}) Value {
+	switch v := v.(type) {
+	case nil:
+		return Value{}
+	case Value:
+		return v
+	case bool:
+		return Bool(v)
+	case string:
+		return String(v)
+	case float64:
+		return Number(v)
+	case float32:
+		return Number(float64(v))
+	case int:
+		return Number(float64(v))
+	case int8:
+		return Number(float64(v))
+	case int16:
+		return Number(float64(v))
+	case int32:
+		return Number(float64(v))
+	case int64:
+		return Number(float64(v))
+	case uint:
+		return Number(float64(v))
+	case uint8:
+		return Number(float64(v))
+	case uint16:
+		return Number(float64(v))
+	case uint32:
+		return Number(float64(v))
+	case uint64:
+		return Number(float64(v))
+	}
+	return Object(v)
+}
+
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+func (v Value) IsNil() bool {
+	return v.kind == NilKind
+}
+
+// AsBool returns the boolean held by v, false if v isn't a bool
+func (v Value) AsBool() bool {
+	return v.kind == BoolKind && v.num != 0
+}
+
+// AsNumber returns the number held by v, 0 if v isn't a number
+func (v Value) AsNumber() float64 {
+	if v.kind != NumberKind {
+		return 0
+	}
+	return v.num
+}
+
+// AsString returns the string held by v, "" if v isn't a string
+func (v Value) AsString() string {
+	s, _ := v.obj.(string)
+	return s
+}
+
+// AsObject returns the Go value held by v, nil if v isn't an object
+func (v Value) AsObject() interface{} {
+	if v.kind != ObjectKind {
+		return nil
+	}
+	return v.obj
+}
+
+// Interface converts v back to a Go value: nil, bool, float64, string
+// or the object itself
+func (v Value) Interface() interface{} {
+	switch v.kind {
+	case BoolKind:
+		return v.AsBool()
+	case NumberKind:
+		return v.num
+	}
+	return v.obj
+}
+
+// String formats v the way print displays it
+func (v Value) String() string {
+	switch v.kind {
+	case NilKind:
+		return "nil"
+	case BoolKind:
+		return strconv.FormatBool(v.AsBool())
+	case NumberKind:
+		return strconv.FormatFloat(v.num, 'f', -1, 64)
+	case StringKind:
+		return v.AsString()
+	}
+	return fmt.Sprint(v.obj)
+}
+
+// Stringify formats v the way print displays it
+func Stringify(v Value) string {
+	return v.String()
+}
+
+func isTruthy(v Value) bool {
+	switch v.kind {
+	case NilKind:
+		return false
+	case BoolKind:
+		return v.num != 0
+	}
+	return true
+}
+
+func isEqual(l, r Value) (bool, error) {
+	if l.kind == r.kind {
+		switch l.kind {
+		case BoolKind, NumberKind:
+			return l.num == r.num, nil
+		case StringKind:
+			return l.obj == r.obj, nil
+		}
+	}
+	return false, &RuntimeError{
+		Msg: fmt.Sprintf("cannot compare %s and %s", typeName(l), typeName(r)),
+	}
+}
@@ -0,0 +1,57 @@
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+func TestValueOf(t *testing.T) {
+	lst := &interpreter.List{}
+	tests := []struct {
+		in   interface{}
+		kind interpreter.Kind
+		out  interface{}
+	}{
+		{nil, interpreter.NilKind, nil},
+		{true, interpreter.BoolKind, true},
+		{false, interpreter.BoolKind, false},
+		{42, interpreter.NumberKind, 42.0},
+		{uint8(7), interpreter.NumberKind, 7.0},
+		{float32(0.5), interpreter.NumberKind, 0.5},
+		{"lox", interpreter.StringKind, "lox"},
+		{interpreter.Number(3), interpreter.NumberKind, 3.0},
+		{lst, interpreter.ObjectKind, lst},
+	}
+	for _, tt := range tests {
+		v := interpreter.ValueOf(tt.in)
+		if v.Kind() != tt.kind || v.Interface() != tt.out {
+			t.Errorf("ValueOf(%#v) = %v %#v, expected %v %#v", tt.in, v.Kind(), v.Interface(), tt.kind, tt.out)
+		}
+	}
+}
+
+func TestArithmeticAllocs(t *testing.T) {
+	interp := interpreter.New()
+	if err := interp.Exec("var x = 3; var y = 4;"); err != nil {
+		t.Fatal(err)
+	}
+	eval, err := interpreter.Evaluator(interp, "(x * x + y * y) / 2 - 1 <= 12.5 == true")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v interpreter.Value
+	allocs := testing.AllocsPerRun(100, func() {
+		v, err = eval()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.AsBool() {
+		t.Errorf("got %v, expected true", v)
+	}
+	if allocs != 0 {
+		t.Errorf("got %v allocations, expected none", allocs)
+	}
+}
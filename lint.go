@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jrouviere/golox/lint"
+)
+
+func lintCmd(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the findings as a JSON array")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "golox lint: no files given")
+		return 2
+	}
+
+	code := 0
+	findings := []lint.Finding{}
+	for _, path := range flags.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+			continue
+		}
+		res, err := lint.Source(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			code = 1
+			continue
+		}
+		for _, f := range res {
+			f.File = path
+			findings = append(findings, f)
+		}
+	}
+	if len(findings) > 0 {
+		code = 1
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(findings); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return code
+	}
+	for _, f := range findings {
+		fmt.Println(f)
+	}
+	return code
+}
@@ -0,0 +1,314 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Info    Severity = "info"
+)
+
+// rules IDs
+const (
+	UnusedVariable    = "unused-variable"
+	UnreachableCode   = "unreachable-code"
+	Shadowing         = "shadowing"
+	AssignInCondition = "assign-in-condition"
+	SelfComparison    = "self-comparison"
+)
+
+var severities = map[string]Severity{
+	UnusedVariable:    Warning,
+	UnreachableCode:   Warning,
+	Shadowing:         Info,
+	AssignInCondition: Warning,
+	SelfComparison:    Warning,
+}
+
+type Finding struct {
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s (%s)", f.File, f.Line, f.Severity, f.Message, f.Rule)
+}
+
+// ignoreDirective suppresses findings on the line of the comment, or on
+// the next line when the comment is alone on its line:
+//
+//	var unused = 1; // lint:ignore unused-variable
+//	// lint:ignore shadowing, unused-variable
+//	var a = 2;
+const ignoreDirective = "// lint:ignore "
+
+// Source parses src and returns the findings not suppressed by a
+// lint:ignore comment, sorted by line
+func Source(src []byte) ([]Finding, error) {
+	scanner := parser.NewScanner(string(src))
+	tokens, err := scanner.ScanWithComments()
+	if err != nil {
+		return nil, err
+	}
+	stmts, err := parser.New(tokens).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	// line -> rules ignored on this line
+	ignored := make(map[int]map[string]bool)
+	for _, c := range scanner.Comments() {
+		if !strings.HasPrefix(c.Text, ignoreDirective) {
+			continue
+		}
+		line := c.Line
+		if !c.Trailing {
+			line++
+		}
+		if ignored[line] == nil {
+			ignored[line] = make(map[string]bool)
+		}
+		for _, rule := range strings.Split(strings.TrimPrefix(c.Text, ignoreDirective), ",") {
+			ignored[line][strings.TrimSpace(rule)] = true
+		}
+	}
+
+	var findings []Finding
+	for _, f := range Check(stmts) {
+		if !ignored[f.Line][f.Rule] {
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// Check runs every rule on stmts and returns the findings sorted by line
+func Check(stmts []parser.Stmt) []Finding {
+	c := &checker{}
+	c.push()
+	c.stmts(stmts)
+	c.pop()
+
+	sort.SliceStable(c.findings, func(i, j int) bool {
+		return c.findings[i].Line < c.findings[j].Line
+	})
+	return c.findings
+}
+
+type decl struct {
+	name *parser.Token
+	used bool
+	// only VarDecl are reported when unused
+	isVar bool
+}
+
+type checker struct {
+	findings []Finding
+	// innermost scope last, the first one holds the globals
+	scopes []map[string]*decl
+	// index of the first scope of the function being checked
+	fun int
+}
+
+func (c *checker) report(line int, rule string, format string, v ...interface{}) {
+	c.findings = append(c.findings, Finding{
+		Line:     line,
+		Rule:     rule,
+		Severity: severities[rule],
+		Message:  fmt.Sprintf(format, v...),
+	})
+}
+
+func (c *checker) push() {
+	c.scopes = append(c.scopes, make(map[string]*decl))
+}
+
+func (c *checker) pop() {
+	scope := c.scopes[len(c.scopes)-1]
+	c.scopes = c.scopes[:len(c.scopes)-1]
+
+	// globals can be read from anywhere, even before being declared
+	if len(c.scopes) == 0 {
+		return
+	}
+	for _, d := range scope {
+		if d.isVar && !d.used {
+			c.report(d.name.Line, UnusedVariable, "variable %s is declared but never used", d.name.Lexeme)
+		}
+	}
+}
+
+func (c *checker) declare(name *parser.Token, isVar bool) {
+	if outer := c.lookup(name.Lexeme, len(c.scopes)-2); outer != nil {
+		c.report(name.Line, Shadowing, "%s shadows the declaration on line %d", name.Lexeme, outer.name.Line)
+	}
+	c.scopes[len(c.scopes)-1][name.Lexeme] = &decl{name: name, isVar: isVar}
+}
+
+func (c *checker) use(name string) {
+	if d := c.lookup(name, len(c.scopes)-1); d != nil {
+		d.used = true
+	}
+}
+
+// lookup returns the declaration of name seen from the scope at idx:
+// the innermost one in the scopes of the current function from idx
+// outwards, or a global. Functions don't see the locals of the
+// functions around them.
+func (c *checker) lookup(name string, idx int) *decl {
+	for i := idx; i >= c.fun; i-- {
+		if d, ok := c.scopes[i][name]; ok {
+			return d
+		}
+	}
+	if c.fun > 0 {
+		return c.scopes[0][name]
+	}
+	return nil
+}
+
+func (c *checker) stmts(stmts []parser.Stmt) {
+	for idx, s := range stmts {
+		c.stmt(s)
+
+		if _, ok := s.(*parser.ReturnStmt); ok && idx+1 < len(stmts) {
+			c.report(stmts[idx+1].Line(), UnreachableCode, "unreachable code after return")
+		}
+	}
+}
+
+func (c *checker) stmt(s parser.Stmt) {
+	switch s := s.(type) {
+	case *parser.PrintStmt:
+		c.expr(s.Value)
+	case *parser.ReturnStmt:
+		if s.Value != nil {
+			c.expr(s.Value)
+		}
+	case *parser.ThrowStmt:
+		c.expr(s.Value)
+	case *parser.ExprStmt:
+		c.expr(s.Value)
+	case *parser.VarDecl:
+		if s.Init != nil {
+			c.expr(s.Init)
+		}
+		c.declare(s.Name, true)
+	case *parser.ImportStmt:
+		c.declare(s.Name, false)
+	case *parser.FunStmt:
+		c.declare(s.Name, false)
+		fun := c.fun
+		c.push()
+		c.fun = len(c.scopes) - 1
+		for _, p := range s.Params {
+			c.declare(p, false)
+		}
+		c.stmt(s.Body)
+		c.pop()
+		c.fun = fun
+	case *parser.Block:
+		c.push()
+		c.stmts(s.Statements)
+		c.pop()
+	case *parser.IfStmt:
+		c.condition(s.Expr, "if")
+		c.stmt(s.ThenBrch)
+		if s.ElseBrch != nil {
+			c.stmt(s.ElseBrch)
+		}
+	case *parser.WhileStmt:
+		c.condition(s.Expr, "while")
+		c.stmt(s.Body)
+	case *parser.ForStmt:
+		c.push()
+		if s.Init != nil {
+			c.stmt(s.Init)
+		}
+		if s.Cond != nil {
+			c.condition(s.Cond, "for")
+		}
+		if s.Incr != nil {
+			c.expr(s.Incr)
+		}
+		c.stmt(s.Body)
+		c.pop()
+	case *parser.TryStmt:
+		c.stmt(s.Body)
+		if s.CatchBody != nil {
+			c.push()
+			c.declare(s.CatchName, false)
+			c.stmt(s.CatchBody)
+			c.pop()
+		}
+		if s.FinallyBody != nil {
+			c.stmt(s.FinallyBody)
+		}
+	}
+}
+
+func (c *checker) condition(cond parser.Expr, kind string) {
+	if a, ok := cond.(*parser.Assign); ok {
+		c.report(a.Line(), AssignInCondition, "assignment to %s used as %s condition, did you mean ==?", a.Name.Lexeme, kind)
+	}
+	c.expr(cond)
+}
+
+func (c *checker) expr(e parser.Expr) {
+	parser.Inspect(e, func(n parser.Node) bool {
+		switch n := n.(type) {
+		case *parser.Variable:
+			c.use(n.Name.Lexeme)
+		case *parser.BinaryExpr:
+			c.selfComparison(n)
+		}
+		return true
+	})
+}
+
+// result of comparing a value with itself, for each operator
+var selfResults = map[parser.TokenType]bool{
+	parser.EQUAL_EQUAL:   true,
+	parser.BANG_EQUAL:    false,
+	parser.LESS:          false,
+	parser.LESS_EQUAL:    true,
+	parser.GREATER:       false,
+	parser.GREATER_EQUAL: true,
+}
+
+// selfComparison reports a literal compared with itself, which gives
+// the same result each time, or a runtime error when its type can't
+// be compared that way
+func (c *checker) selfComparison(n *parser.BinaryExpr) {
+	result, ok := selfResults[n.Op.Typ]
+	l, lok := n.Left.(*parser.LiteralExpr)
+	r, rok := n.Right.(*parser.LiteralExpr)
+	if !ok || !lok || !rok || l.Value.Typ != r.Value.Typ || l.Value.Lexeme != r.Value.Lexeme {
+		return
+	}
+
+	valid := false
+	switch l.Value.Typ {
+	case parser.NUMBER, parser.STRING:
+		valid = true
+	case parser.TRUE, parser.FALSE:
+		// booleans are only equal or not
+		valid = n.Op.Typ == parser.EQUAL_EQUAL || n.Op.Typ == parser.BANG_EQUAL
+	}
+	if !valid {
+		c.report(n.Line(), SelfComparison, "comparison of %s with itself is a runtime error", l.Value.Lexeme)
+		return
+	}
+	c.report(n.Line(), SelfComparison, "comparison of %s with itself is always %t", l.Value.Lexeme, result)
+}
@@ -0,0 +1,82 @@
+package lint_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jrouviere/golox/lint"
+)
+
+func TestSource(t *testing.T) {
+	src, err := os.ReadFile("testdata/findings.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := lint.Source(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type finding struct {
+		Line int
+		Rule string
+	}
+	var got []finding
+	for _, f := range findings {
+		got = append(got, finding{f.Line, f.Rule})
+	}
+	expected := []finding{
+		{4, lint.UnusedVariable},
+		{11, lint.UnreachableCode},
+		{14, lint.Shadowing},
+		{17, lint.Shadowing},
+		{24, lint.AssignInCondition},
+		{25, lint.AssignInCondition},
+		{27, lint.SelfComparison},
+		{28, lint.SelfComparison},
+		{38, lint.SelfComparison},
+		{39, lint.SelfComparison},
+		{40, lint.SelfComparison},
+		{41, lint.SelfComparison},
+		{42, lint.SelfComparison},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected findings:\n%v\nexpected:\n%v", findings, expected)
+	}
+}
+
+func TestSelfComparison(t *testing.T) {
+	for _, tc := range []struct {
+		src string
+		msg string
+	}{
+		{`print 1 == 1;`, "comparison of 1 with itself is always true"},
+		{`print "a" != "a";`, `comparison of "a" with itself is always false`},
+		{`print 1 < 1;`, "comparison of 1 with itself is always false"},
+		{`print 1 <= 1;`, "comparison of 1 with itself is always true"},
+		{`print true == true;`, "comparison of true with itself is always true"},
+		{`print nil == nil;`, "comparison of nil with itself is a runtime error"},
+		{`print false > false;`, "comparison of false with itself is a runtime error"},
+	} {
+		findings, err := lint.Source([]byte(tc.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(findings) != 1 || findings[0].Message != tc.msg {
+			t.Errorf("%s: got %v, expected %q", tc.src, findings, tc.msg)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	src := []byte("fun f(a) {\n  var b = a;\n  return b;\n}\nprint f(1);\n")
+	findings, err := lint.Source(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jrouviere/golox/lsp"
+)
+
+func lspCmd(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "golox lsp: takes no arguments")
+		return 2
+	}
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
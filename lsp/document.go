@@ -0,0 +1,381 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jrouviere/golox/parser"
+)
+
+// document is the analysis of an open file, redone on every change
+type document struct {
+	uri  string
+	text string
+
+	tokens []*parser.Token
+	// start position of every token
+	pos   map[*parser.Token]Position
+	stmts []parser.Stmt
+	// scanning or syntax error, stmts is empty when set
+	err error
+
+	// top level declarations, in source order
+	globals []*declaration
+	// declaration of every resolved identifier, including the
+	// identifiers being declared
+	refs map[*parser.Token]*declaration
+}
+
+type declKind int
+
+const (
+	declVar declKind = iota
+	declFun
+	declParam
+	declImport
+	declCatch
+)
+
+type declaration struct {
+	name *parser.Token
+	kind declKind
+	// set for declFun, and for declParam with the function it belongs to
+	fun *parser.FunStmt
+	// set for declVar and declImport
+	stmt parser.Stmt
+}
+
+func (d *declaration) signature() string {
+	switch d.kind {
+	case declFun:
+		return "fun " + d.name.Lexeme + "(" + params(d.fun) + ")"
+	case declParam:
+		return "parameter " + d.name.Lexeme + " of " + d.fun.Name.Lexeme
+	case declImport:
+		return "import " + d.stmt.(*parser.ImportStmt).Path.Lexeme + " as " + d.name.Lexeme
+	case declCatch:
+		return "catch (" + d.name.Lexeme + ")"
+	}
+	return "var " + d.name.Lexeme
+}
+
+func params(fun *parser.FunStmt) string {
+	var names []string
+	for _, p := range fun.Params {
+		names = append(names, p.Lexeme)
+	}
+	return strings.Join(names, ", ")
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{
+		uri:  uri,
+		text: text,
+		pos:  make(map[*parser.Token]Position),
+		refs: make(map[*parser.Token]*declaration),
+	}
+
+	tokens, err := parser.NewScanner(text).ScanWithTrivia()
+	if err != nil {
+		d.err = err
+		return d
+	}
+	d.tokens = tokens
+	d.locate()
+
+	stmts, err := parser.New(tokens).Parse()
+	if err != nil {
+		d.err = err
+		return d
+	}
+	d.stmts = stmts
+	d.resolve()
+	return d
+}
+
+// locate computes the position of every token, concatenating the
+// tokens with their trivia gives back the whole text
+func (d *document) locate() {
+	var cur Position
+	advance := func(s string) {
+		for _, r := range s {
+			switch {
+			case r == '\n':
+				cur.Line++
+				cur.Character = 0
+			case r >= 0x10000:
+				cur.Character += 2
+			default:
+				cur.Character++
+			}
+		}
+	}
+	for _, tok := range d.tokens {
+		advance(tok.Leading)
+		d.pos[tok] = cur
+		advance(tok.Lexeme)
+		advance(tok.Trailing)
+	}
+}
+
+// rangeOf returns the range covered by tok, which must be on one line
+func (d *document) rangeOf(tok *parser.Token) Range {
+	start := d.pos[tok]
+	end := start
+	for _, r := range tok.Lexeme {
+		if r >= 0x10000 {
+			end.Character += 2
+		} else {
+			end.Character++
+		}
+	}
+	return Range{Start: start, End: end}
+}
+
+// tokenAt returns the identifier at p, or nil
+func (d *document) tokenAt(p Position) *parser.Token {
+	for _, tok := range d.tokens {
+		if tok.Typ != parser.IDENTIFIER {
+			continue
+		}
+		r := d.rangeOf(tok)
+		if r.Start.Line == p.Line && r.Start.Character <= p.Character && p.Character <= r.End.Character {
+			return tok
+		}
+	}
+	return nil
+}
+
+// diagnostics converts the scanning or syntax error of the document
+func (d *document) diagnostics() []Diagnostic {
+	diags := []Diagnostic{}
+	if d.err == nil {
+		return diags
+	}
+
+	var rng Range
+	var msg string
+	var scanErr *parser.ScanningError
+	var syntaxErr *parser.SyntaxError
+	switch {
+	case errors.As(d.err, &scanErr):
+		rng = d.lineRange(scanErr.Line - 1)
+		msg = scanErr.Msg
+	case errors.As(d.err, &syntaxErr):
+		if syntaxErr.Token.Typ == parser.EOF {
+			rng = Range{Start: d.pos[syntaxErr.Token], End: d.pos[syntaxErr.Token]}
+		} else {
+			rng = d.rangeOf(syntaxErr.Token)
+		}
+		msg = syntaxErr.Msg
+	default:
+		msg = d.err.Error()
+	}
+	return append(diags, Diagnostic{
+		Range:    rng,
+		Severity: SeverityError,
+		Source:   "golox",
+		Message:  msg,
+	})
+}
+
+// lineRange covers the whole zero based line
+func (d *document) lineRange(line int) Range {
+	lines := strings.Split(d.text, "\n")
+	if line < 0 || line >= len(lines) {
+		return Range{}
+	}
+	return Range{
+		Start: Position{Line: line},
+		End:   Position{Line: line, Character: len([]rune(lines[line]))},
+	}
+}
+
+// symbols returns the functions and variables declared in stmts, the
+// declarations inside a function are its children
+func (d *document) symbols(stmts []parser.Stmt) []DocumentSymbol {
+	syms := []DocumentSymbol{}
+	for _, s := range stmts {
+		parser.Inspect(s, func(n parser.Node) bool {
+			switch n := n.(type) {
+			case *parser.VarDecl:
+				rng := d.rangeOf(n.Name)
+				syms = append(syms, DocumentSymbol{
+					Name:           n.Name.Lexeme,
+					Kind:           SymbolVariable,
+					Range:          rng,
+					SelectionRange: rng,
+				})
+			case *parser.FunStmt:
+				sel := d.rangeOf(n.Name)
+				rng := sel
+				var body []parser.Stmt
+				if b, ok := n.Body.(*parser.Block); ok {
+					rng.End = d.rangeOf(b.Rbrace).End
+					body = b.Statements
+				} else {
+					body = []parser.Stmt{n.Body}
+				}
+				syms = append(syms, DocumentSymbol{
+					Name:           n.Name.Lexeme,
+					Detail:         "(" + params(n) + ")",
+					Kind:           SymbolFunction,
+					Range:          rng,
+					SelectionRange: sel,
+					Children:       d.symbols(body),
+				})
+				return false
+			}
+			return true
+		})
+	}
+	return syms
+}
+
+// resolve binds every identifier to its declaration, following the
+// scoping rules of the interpreter. Globals can be used before being
+// declared, from inside functions.
+func (d *document) resolve() {
+	r := &resolver{doc: d, globals: make(map[string]*declaration)}
+	for _, s := range d.stmts {
+		switch s := s.(type) {
+		case *parser.VarDecl:
+			r.global(&declaration{name: s.Name, kind: declVar, stmt: s})
+		case *parser.FunStmt:
+			r.global(&declaration{name: s.Name, kind: declFun, fun: s})
+		case *parser.ImportStmt:
+			r.global(&declaration{name: s.Name, kind: declImport, stmt: s})
+		}
+	}
+	for _, s := range d.stmts {
+		r.stmt(s)
+	}
+}
+
+type resolver struct {
+	doc     *document
+	globals map[string]*declaration
+	// local scopes, innermost last
+	scopes []map[string]*declaration
+}
+
+func (r *resolver) global(decl *declaration) {
+	r.doc.globals = append(r.doc.globals, decl)
+	r.doc.refs[decl.name] = decl
+	if _, ok := r.globals[decl.name.Lexeme]; !ok {
+		r.globals[decl.name.Lexeme] = decl
+	}
+}
+
+func (r *resolver) declare(decl *declaration) {
+	if len(r.scopes) == 0 {
+		// already declared by the first pass
+		return
+	}
+	r.scopes[len(r.scopes)-1][decl.name.Lexeme] = decl
+	r.doc.refs[decl.name] = decl
+}
+
+func (r *resolver) use(name *parser.Token) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if decl, ok := r.scopes[i][name.Lexeme]; ok {
+			r.doc.refs[name] = decl
+			return
+		}
+	}
+	if decl, ok := r.globals[name.Lexeme]; ok {
+		r.doc.refs[name] = decl
+	}
+}
+
+func (r *resolver) push() {
+	r.scopes = append(r.scopes, make(map[string]*declaration))
+}
+
+func (r *resolver) pop() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *resolver) stmt(s parser.Stmt) {
+	switch s := s.(type) {
+	case *parser.PrintStmt:
+		r.expr(s.Value)
+	case *parser.ReturnStmt:
+		if s.Value != nil {
+			r.expr(s.Value)
+		}
+	case *parser.ThrowStmt:
+		r.expr(s.Value)
+	case *parser.ExprStmt:
+		r.expr(s.Value)
+	case *parser.VarDecl:
+		if s.Init != nil {
+			r.expr(s.Init)
+		}
+		r.declare(&declaration{name: s.Name, kind: declVar, stmt: s})
+	case *parser.ImportStmt:
+		r.declare(&declaration{name: s.Name, kind: declImport, stmt: s})
+	case *parser.FunStmt:
+		r.declare(&declaration{name: s.Name, kind: declFun, fun: s})
+		r.push()
+		for _, p := range s.Params {
+			r.declare(&declaration{name: p, kind: declParam, fun: s})
+		}
+		r.stmt(s.Body)
+		r.pop()
+	case *parser.Block:
+		r.push()
+		for _, s := range s.Statements {
+			r.stmt(s)
+		}
+		r.pop()
+	case *parser.IfStmt:
+		r.expr(s.Expr)
+		r.stmt(s.ThenBrch)
+		if s.ElseBrch != nil {
+			r.stmt(s.ElseBrch)
+		}
+	case *parser.WhileStmt:
+		r.expr(s.Expr)
+		r.stmt(s.Body)
+	case *parser.ForStmt:
+		r.push()
+		if s.Init != nil {
+			r.stmt(s.Init)
+		}
+		if s.Cond != nil {
+			r.expr(s.Cond)
+		}
+		if s.Incr != nil {
+			r.expr(s.Incr)
+		}
+		r.stmt(s.Body)
+		r.pop()
+	case *parser.TryStmt:
+		r.stmt(s.Body)
+		if s.CatchBody != nil {
+			r.push()
+			r.declare(&declaration{name: s.CatchName, kind: declCatch})
+			r.stmt(s.CatchBody)
+			r.pop()
+		}
+		if s.FinallyBody != nil {
+			r.stmt(s.FinallyBody)
+		}
+	default:
+		panic(fmt.Sprintf("lsp: unexpected statement %T", s))
+	}
+}
+
+func (r *resolver) expr(e parser.Expr) {
+	parser.Inspect(e, func(n parser.Node) bool {
+		switch n := n.(type) {
+		case *parser.Variable:
+			r.use(n.Name)
+		case *parser.Assign:
+			r.use(n.Name)
+		}
+		return true
+	})
+}
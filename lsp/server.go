@@ -0,0 +1,287 @@
+// Package lsp implements a Language Server Protocol server for Lox,
+// speaking JSON-RPC over a pair of streams such as stdin and stdout.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jrouviere/golox/internal/wire"
+	"github.com/jrouviere/golox/interpreter"
+	"github.com/jrouviere/golox/parser"
+)
+
+// ErrNoShutdown is returned by Serve when the client sends exit
+// without asking for a shutdown first
+var ErrNoShutdown = errors.New("lsp: exit without shutdown")
+
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	docs     map[string]*document
+	builtins []string
+	shutdown bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:       bufio.NewReader(in),
+		out:      out,
+		docs:     make(map[string]*document),
+		builtins: interpreter.New().Globals().Names(),
+	}
+}
+
+// Serve handles messages until the client sends exit or closes the
+// input stream
+func (s *Server) Serve() error {
+	for {
+		body, err := wire.Read(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.reply(nil, nil, &rpcError{Code: parseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrNoShutdown
+			}
+			return nil
+		}
+
+		result, rerr := s.handle(&msg)
+		// notifications don't get a response
+		if msg.ID == nil {
+			continue
+		}
+		if err := s.reply(msg.ID, result, rerr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) reply(id json.RawMessage, result interface{}, rerr *rpcError) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := response{JSONRPC: "2.0", ID: id, Error: rerr}
+	if rerr == nil {
+		res, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = res
+	}
+	return wire.Write(s.out, resp)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return wire.Write(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) handle(msg *message) (interface{}, *rpcError) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				// full document sync
+				"textDocumentSync":       1,
+				"documentSymbolProvider": true,
+				"definitionProvider":     true,
+				"hoverProvider":          true,
+				"completionProvider":     map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "golox"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := decode(msg, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := decode(msg, &params); err != nil {
+			return nil, err
+		}
+		// with full sync the last change holds the whole text
+		if n := len(params.ContentChanges); n > 0 {
+			return nil, s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := decode(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.publish(params.TextDocument.URI, []Diagnostic{})
+
+	case "textDocument/documentSymbol":
+		var params DocumentSymbolParams
+		if err := decode(msg, &params); err != nil {
+			return nil, err
+		}
+		doc, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return doc.symbols(doc.stmts), nil
+	case "textDocument/definition":
+		doc, decl, _, err := s.lookup(msg)
+		if err != nil || decl == nil {
+			return nil, err
+		}
+		return Location{URI: doc.uri, Range: doc.rangeOf(decl.name)}, nil
+	case "textDocument/hover":
+		return s.hover(msg)
+	case "textDocument/completion":
+		return s.completion(msg)
+	}
+
+	if msg.ID == nil {
+		// unknown notifications are ignored
+		return nil, nil
+	}
+	return nil, &rpcError{Code: methodNotFound, Message: fmt.Sprintf("method not found: %s", msg.Method)}
+}
+
+func decode(msg *message, v interface{}) *rpcError {
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		return &rpcError{Code: invalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// update analyses the new text of a document and publishes its
+// diagnostics
+func (s *Server) update(uri, text string) *rpcError {
+	doc := newDocument(uri, text)
+	s.docs[uri] = doc
+	return s.publish(uri, doc.diagnostics())
+}
+
+func (s *Server) publish(uri string, diags []Diagnostic) *rpcError {
+	err := s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diags,
+	})
+	if err != nil {
+		return &rpcError{Code: invalidRequest, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) document(uri string) (*document, *rpcError) {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil, &rpcError{Code: invalidParams, Message: fmt.Sprintf("unknown document: %s", uri)}
+	}
+	return doc, nil
+}
+
+// lookup returns the identifier at the position of a request, and its
+// declaration if it could be resolved
+func (s *Server) lookup(msg *message) (*document, *declaration, *parser.Token, *rpcError) {
+	var params TextDocumentPositionParams
+	if err := decode(msg, &params); err != nil {
+		return nil, nil, nil, err
+	}
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	tok := doc.tokenAt(params.Position)
+	if tok == nil {
+		return doc, nil, nil, nil
+	}
+	return doc, doc.refs[tok], tok, nil
+}
+
+func (s *Server) hover(msg *message) (interface{}, *rpcError) {
+	doc, decl, tok, err := s.lookup(msg)
+	if err != nil || tok == nil {
+		return nil, err
+	}
+
+	var text string
+	switch {
+	case decl != nil:
+		text = fmt.Sprintf("```lox\n%s\n```\ndeclared on line %d", decl.signature(), decl.name.Line)
+	case s.isBuiltin(tok.Lexeme):
+		text = fmt.Sprintf("```lox\n%s\n```\nbuiltin", tok.Lexeme)
+	default:
+		return nil, nil
+	}
+	rng := doc.rangeOf(tok)
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: text},
+		Range:    &rng,
+	}, nil
+}
+
+func (s *Server) isBuiltin(name string) bool {
+	for _, b := range s.builtins {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// completion offers the keywords, the builtins and the globals of the
+// document, wherever the cursor is
+func (s *Server) completion(msg *message) (interface{}, *rpcError) {
+	var params TextDocumentPositionParams
+	if err := decode(msg, &params); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	list := CompletionList{Items: []CompletionItem{}}
+	for _, kw := range parser.Keywords() {
+		list.Items = append(list.Items, CompletionItem{Label: kw, Kind: CompletionKeyword})
+	}
+	for _, b := range s.builtins {
+		list.Items = append(list.Items, CompletionItem{Label: b, Kind: CompletionFunction, Detail: "builtin"})
+	}
+	seen := make(map[string]bool)
+	for _, decl := range doc.globals {
+		if seen[decl.name.Lexeme] {
+			continue
+		}
+		seen[decl.name.Lexeme] = true
+
+		kind := CompletionVariable
+		switch decl.kind {
+		case declFun:
+			kind = CompletionFunction
+		case declImport:
+			kind = CompletionModule
+		}
+		list.Items = append(list.Items, CompletionItem{Label: decl.name.Lexeme, Kind: kind, Detail: decl.signature()})
+	}
+	return list, nil
+}
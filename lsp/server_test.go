@@ -0,0 +1,296 @@
+package lsp_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jrouviere/golox/lsp"
+)
+
+// client drives a Server the way an editor would
+type client struct {
+	t      *testing.T
+	w      io.WriteCloser
+	r      *bufio.Reader
+	nextID int
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	c := &client{t: t, w: inW, r: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		err := lsp.NewServer(inR, outW).Serve()
+		outW.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+func (c *client) send(v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+type incoming struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *client) receive() incoming {
+	length := 0
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "Content-Length: ") {
+			length, _ = strconv.Atoi(strings.TrimPrefix(line, "Content-Length: "))
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		c.t.Fatal(err)
+	}
+
+	var msg incoming
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// call sends a request and decodes its result into res
+func (c *client) call(method string, params, res interface{}) {
+	c.nextID++
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+
+	msg := c.receive()
+	if msg.ID == nil || *msg.ID != c.nextID {
+		c.t.Fatalf("%s: unexpected message %+v", method, msg)
+	}
+	if msg.Error != nil {
+		c.t.Fatalf("%s: %s", method, msg.Error.Message)
+	}
+	if res != nil {
+		if err := json.Unmarshal(msg.Result, res); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// diagnostics waits for the next published diagnostics
+func (c *client) diagnostics() lsp.PublishDiagnosticsParams {
+	msg := c.receive()
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("expected diagnostics, got %+v", msg)
+	}
+	var params lsp.PublishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		c.t.Fatal(err)
+	}
+	return params
+}
+
+func (c *client) exit() {
+	c.call("shutdown", nil, nil)
+	c.notify("exit", nil)
+	c.w.Close()
+	if err := <-c.done; err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+const uri = "file:///test.lox"
+
+const src = `var total = 0;
+
+fun add(a, b) {
+  var sum = a + b;
+  return sum;
+}
+
+total = add(total, sqrt(4));
+print total;
+`
+
+func position(line, char int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     map[string]int{"line": line, "character": char},
+	}
+}
+
+func TestSession(t *testing.T) {
+	c := newClient(t)
+
+	var init struct {
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	c.call("initialize", map[string]interface{}{}, &init)
+	if init.Capabilities["hoverProvider"] != true {
+		t.Errorf("unexpected capabilities: %v", init.Capabilities)
+	}
+	c.notify("initialized", map[string]interface{}{})
+
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "version": 1, "text": src},
+	})
+	if diags := c.diagnostics(); diags.URI != uri || len(diags.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics: %+v", diags)
+	}
+
+	t.Run("symbols", func(t *testing.T) {
+		var syms []lsp.DocumentSymbol
+		c.call("textDocument/documentSymbol", map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri},
+		}, &syms)
+
+		if len(syms) != 2 || syms[0].Name != "total" || syms[1].Name != "add" {
+			t.Fatalf("unexpected symbols: %+v", syms)
+		}
+		add := syms[1]
+		if add.Kind != lsp.SymbolFunction || add.Range.End.Line != 5 {
+			t.Errorf("unexpected function symbol: %+v", add)
+		}
+		if len(add.Children) != 1 || add.Children[0].Name != "sum" {
+			t.Errorf("unexpected children: %+v", add.Children)
+		}
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		tests := []struct {
+			line, char int
+			expected   lsp.Position
+		}{
+			// sum in return sum
+			{4, 10, lsp.Position{Line: 3, Character: 6}},
+			// a in a + b
+			{3, 12, lsp.Position{Line: 2, Character: 8}},
+			// total, the global used as argument
+			{7, 13, lsp.Position{Line: 0, Character: 4}},
+			// add, called before its body was executed
+			{7, 8, lsp.Position{Line: 2, Character: 4}},
+		}
+		for _, tt := range tests {
+			var loc lsp.Location
+			c.call("textDocument/definition", position(tt.line, tt.char), &loc)
+			if loc.URI != uri || loc.Range.Start != tt.expected {
+				t.Errorf("%d:%d: unexpected location %+v, expected %+v", tt.line, tt.char, loc, tt.expected)
+			}
+		}
+
+		var loc *lsp.Location
+		c.call("textDocument/definition", position(7, 20), &loc)
+		if loc != nil {
+			t.Errorf("builtins have no definition, got %+v", loc)
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		var hover lsp.Hover
+		c.call("textDocument/hover", position(7, 9), &hover)
+		if !strings.Contains(hover.Contents.Value, "fun add(a, b)") {
+			t.Errorf("unexpected hover: %q", hover.Contents.Value)
+		}
+
+		c.call("textDocument/hover", position(7, 20), &hover)
+		if !strings.Contains(hover.Contents.Value, "builtin") {
+			t.Errorf("unexpected hover: %q", hover.Contents.Value)
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		var list lsp.CompletionList
+		c.call("textDocument/completion", position(8, 0), &list)
+
+		labels := make(map[string]lsp.CompletionItemKind)
+		for _, item := range list.Items {
+			labels[item.Label] = item.Kind
+		}
+		expected := map[string]lsp.CompletionItemKind{
+			"while": lsp.CompletionKeyword,
+			"clock": lsp.CompletionFunction,
+			"add":   lsp.CompletionFunction,
+			"total": lsp.CompletionVariable,
+		}
+		for label, kind := range expected {
+			if labels[label] != kind {
+				t.Errorf("%s: expected kind %d, got %d", label, kind, labels[label])
+			}
+		}
+		if _, ok := labels["sum"]; ok {
+			t.Errorf("locals must not be offered")
+		}
+	})
+
+	t.Run("diagnostics", func(t *testing.T) {
+		c.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+			"contentChanges": []map[string]string{{"text": "var a = 1;\nprint a +;\n"}},
+		})
+		diags := c.diagnostics()
+		if len(diags.Diagnostics) != 1 {
+			t.Fatalf("expected one diagnostic, got %+v", diags)
+		}
+		if d := diags.Diagnostics[0]; d.Range.Start != (lsp.Position{Line: 1, Character: 9}) {
+			t.Errorf("unexpected diagnostic: %+v", d)
+		}
+
+		c.notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": uri, "version": 3},
+			"contentChanges": []map[string]string{{"text": "print 1;\nprint @;\n"}},
+		})
+		diags = c.diagnostics()
+		if len(diags.Diagnostics) != 1 || diags.Diagnostics[0].Range.Start.Line != 1 {
+			t.Errorf("unexpected diagnostics: %+v", diags)
+		}
+
+		c.notify("textDocument/didClose", map[string]interface{}{
+			"textDocument": map[string]string{"uri": uri},
+		})
+		if diags := c.diagnostics(); len(diags.Diagnostics) != 0 {
+			t.Errorf("closing a document must clear its diagnostics, got %+v", diags)
+		}
+	})
+
+	c.exit()
+}
+
+func TestUnknownMethod(t *testing.T) {
+	c := newClient(t)
+
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "workspace/unknown"})
+	msg := c.receive()
+	if msg.Error == nil || msg.Error.Code != -32601 {
+		t.Errorf("expected method not found, got %+v", msg)
+	}
+
+	c.exit()
+}
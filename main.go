@@ -1,8 +1,58 @@
 package main
 
-import "github.com/jrouviere/golox/interpreter"
+import (
+	"fmt"
+	"os"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+const usage = `usage: golox <command> [arguments]
+
+commands:
+  fmt [--check] files...   format Lox files in place, or list the ones
+                           that are not formatted with --check
+  lint [--json] files...   report unused variables, unreachable code,
+                           shadowing and suspicious conditions
+  lsp                      run the language server on stdin/stdout
+  debug file.lox           run a file under the interactive debugger
+  debug --dap              serve the Debug Adapter Protocol on stdin/stdout
+  profile [--format F] [-o out] file.lox
+                           run a file and report where the time was spent,
+                           F is text (default), collapsed or pprof
+  cover [--lcov out] [--html out] [--merge] files...
+                           run files and report the lines and branches
+                           executed, --merge adds the existing lcov file
+`
 
 func main() {
+	if len(os.Args) < 2 {
+		demo()
+		return
+	}
+
+	var code int
+	switch os.Args[1] {
+	case "fmt":
+		code = fmtCmd(os.Args[2:])
+	case "lint":
+		code = lintCmd(os.Args[2:])
+	case "lsp":
+		code = lspCmd(os.Args[2:])
+	case "debug":
+		code = debugCmd(os.Args[2:])
+	case "profile":
+		code = profileCmd(os.Args[2:])
+	case "cover":
+		code = coverCmd(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		code = 2
+	}
+	os.Exit(code)
+}
+
+func demo() {
 	const input = `
 		var t1 = clock();
 		fun fib(n) {
@@ -6,304 +6,208 @@ import (
 )
 
 type Expr interface {
-	Evaluate(env *Env) (interface{}, error)
-	String() string
+	Node
+	exprNode()
+}
+
+// ExprVisitor is implemented by back ends evaluating expressions to
+// results of type R, VisitExpr dispatches each node to its Visit method
+type ExprVisitor[R any] interface {
+	VisitBinaryExpr(e *BinaryExpr) (R, error)
+	VisitUnaryExpr(e *UnaryExpr) (R, error)
+	VisitLiteralExpr(e *LiteralExpr) (R, error)
+	VisitGroupingExpr(e *GroupingExpr) (R, error)
+	VisitVariable(e *Variable) (R, error)
+	VisitAssign(e *Assign) (R, error)
+	VisitLogical(e *Logical) (R, error)
+	VisitCall(e *Call) (R, error)
+	VisitGetExpr(e *GetExpr) (R, error)
+	VisitSetExpr(e *SetExpr) (R, error)
+}
+
+// VisitExpr calls the Visit method of v for e
+func VisitExpr[R any](e Expr, v ExprVisitor[R]) (R, error) {
+	switch e := e.(type) {
+	case *BinaryExpr:
+		return v.VisitBinaryExpr(e)
+	case *UnaryExpr:
+		return v.VisitUnaryExpr(e)
+	case *LiteralExpr:
+		return v.VisitLiteralExpr(e)
+	case *GroupingExpr:
+		return v.VisitGroupingExpr(e)
+	case *Variable:
+		return v.VisitVariable(e)
+	case *Assign:
+		return v.VisitAssign(e)
+	case *Logical:
+		return v.VisitLogical(e)
+	case *Call:
+		return v.VisitCall(e)
+	case *GetExpr:
+		return v.VisitGetExpr(e)
+	case *SetExpr:
+		return v.VisitSetExpr(e)
+	}
+	panic(fmt.Sprintf("parser: unexpected expression %T", e))
 }
 
-type RuntimeError struct {
-	Msg string
-}
-
-func (e *RuntimeError) Error() string {
-	return "runtime error: " + e.Msg
+type BinaryExpr struct {
+	Left  Expr
+	Op    *Token
+	Right Expr
 }
 
-type BinaryExpr struct {
-	left  Expr
-	op    *Token
-	right Expr
+func (e *BinaryExpr) Line() int {
+	return e.Op.Line
 }
 
 func (e *BinaryExpr) String() string {
-	return "(" + e.op.Lexeme + " " + e.left.String() + " " + e.right.String() + ")"
+	return "(" + e.Op.Lexeme + " " + e.Left.String() + " " + e.Right.String() + ")"
 }
 
-func (e *BinaryExpr) Evaluate(env *Env) (interface{}, error) {
-	l, err := e.left.Evaluate(env)
-	if err != nil {
-		return nil, err
-	}
-	r, err := e.right.Evaluate(env)
-	if err != nil {
-		return nil, err
-	}
-
-	switch e.op.Typ {
-	case PLUS:
-		if allNumbers(l, r) {
-			return l.(float64) + r.(float64), nil
-		}
-		if allStrings(l, r) {
-			return l.(string) + r.(string), nil
-		}
-	case MINUS:
-		if allNumbers(l, r) {
-			return l.(float64) - r.(float64), nil
-		}
-	case STAR:
-		if allNumbers(l, r) {
-			return l.(float64) * r.(float64), nil
-		}
-	case SLASH:
-		if allNumbers(l, r) {
-			return l.(float64) / r.(float64), nil
-		}
-	case EQUAL_EQUAL:
-		return isEqual(l, r)
-	case BANG_EQUAL:
-		eq, err := isEqual(l, r)
-		return !eq, err
-	case LESS_EQUAL:
-		if allNumbers(l, r) {
-			return l.(float64) <= r.(float64), nil
-		}
-	case LESS:
-		if allNumbers(l, r) {
-			return l.(float64) < r.(float64), nil
-		}
-		if allStrings(l, r) {
-			return l.(string) < r.(string), nil
-		}
-	case GREATER_EQUAL:
-		if allNumbers(l, r) {
-			return l.(float64) >= r.(float64), nil
-		}
-	case GREATER:
-		if allNumbers(l, r) {
-			return l.(float64) > r.(float64), nil
-		}
-		if allStrings(l, r) {
-			return l.(string) > r.(string), nil
-		}
-	}
+func (*BinaryExpr) exprNode() {}
 
-	return nil, &RuntimeError{
-		Msg: fmt.Sprintf("unimplemented operation %T %v %T", l, e.op.Lexeme, r),
-	}
+type UnaryExpr struct {
+	Op    *Token
+	Right Expr
 }
 
-type UnaryExpr struct {
-	op    *Token
-	right Expr
+func (e *UnaryExpr) Line() int {
+	return e.Op.Line
 }
 
 func (e *UnaryExpr) String() string {
-	return "(" + e.op.Lexeme + " " + e.right.String() + ")"
+	return "(" + e.Op.Lexeme + " " + e.Right.String() + ")"
 }
 
-func (e *UnaryExpr) Evaluate(env *Env) (interface{}, error) {
-	r, err := e.right.Evaluate(env)
-	if err != nil {
-		return nil, err
-	}
+func (*UnaryExpr) exprNode() {}
 
-	switch e.op.Typ {
-	case MINUS:
-		return -r.(float64), nil
-	}
-	return nil, &RuntimeError{"unimplemented"}
+type LiteralExpr struct {
+	Value *Token
 }
 
-type LiteralExpr struct {
-	op *Token
+func (e *LiteralExpr) Line() int {
+	return e.Value.Line
 }
 
 func (e *LiteralExpr) String() string {
-	return e.op.Lexeme
+	return e.Value.Lexeme
 }
 
-func (e *LiteralExpr) Evaluate(env *Env) (interface{}, error) {
-	switch e.op.Typ {
-	case NIL:
-		return nil, nil
-	case FALSE:
-		return false, nil
-	case TRUE:
-		return true, nil
-	}
-	return e.op.Literal, nil
-}
+func (*LiteralExpr) exprNode() {}
 
 type GroupingExpr struct {
-	expr Expr
+	Expr Expr
 }
 
-func (e *GroupingExpr) String() string {
-	return "(group " + e.expr.String() + ")"
+func (e *GroupingExpr) Line() int {
+	return e.Expr.Line()
 }
 
-func (e *GroupingExpr) Evaluate(env *Env) (interface{}, error) {
-	return e.expr.Evaluate(env)
+func (e *GroupingExpr) String() string {
+	return "(group " + e.Expr.String() + ")"
 }
 
+func (*GroupingExpr) exprNode() {}
+
 type Variable struct {
-	name *Token
+	Name *Token
+	// set by Resolve when the variable is a local, nil for globals
+	Local *Slot
 }
 
-func (e *Variable) String() string {
-	return "(value " + e.name.Lexeme + ")"
+func (e *Variable) Line() int {
+	return e.Name.Line
 }
 
-func (e *Variable) Evaluate(env *Env) (interface{}, error) {
-	return env.Get(e.name.Lexeme)
+func (e *Variable) String() string {
+	return "(value " + e.Name.Lexeme + ")"
 }
 
+func (*Variable) exprNode() {}
+
 type Assign struct {
-	name  *Token
-	value Expr
+	Name  *Token
+	Value Expr
+	// set by Resolve when the variable is a local, nil for globals
+	Local *Slot
 }
 
-func (e *Assign) String() string {
-	return "(assign " + e.name.Lexeme + " " + e.value.String() + ")"
+func (e *Assign) Line() int {
+	return e.Name.Line
 }
 
-func (e *Assign) Evaluate(env *Env) (interface{}, error) {
-	v, err := e.value.Evaluate(env)
-	if err != nil {
-		return nil, err
-	}
-	return v, env.Set(e.name.Lexeme, v)
+func (e *Assign) String() string {
+	return "(assign " + e.Name.Lexeme + " " + e.Value.String() + ")"
 }
 
+func (*Assign) exprNode() {}
+
 type Logical struct {
-	left     Expr
-	operator *Token
-	right    Expr
+	Left     Expr
+	Operator *Token
+	Right    Expr
+}
+
+func (e *Logical) Line() int {
+	return e.Operator.Line
 }
 
 func (e *Logical) String() string {
-	return "(" + e.operator.Lexeme + " " + e.left.String() + ", " + e.right.String() + ")"
+	return "(" + e.Operator.Lexeme + " " + e.Left.String() + ", " + e.Right.String() + ")"
 }
 
-func (e *Logical) Evaluate(env *Env) (interface{}, error) {
-	l, err := e.left.Evaluate(env)
-	if err != nil {
-		return nil, err
-	}
+func (*Logical) exprNode() {}
 
-	if e.operator.Typ == OR {
-		if isTruthy(l) {
-			return l, nil
-		}
-	} else {
-		if !isTruthy(l) {
-			return l, nil
-		}
-	}
-
-	return e.right.Evaluate(env)
+type Call struct {
+	Callee Expr
+	Paren  *Token
+	Args   []Expr
 }
 
-type Call struct {
-	callee Expr
-	paren  *Token
-	args   []Expr
+func (e *Call) Line() int {
+	return e.Callee.Line()
 }
 
 func (e *Call) String() string {
 	var args []string
-	for _, arg := range e.args {
+	for _, arg := range e.Args {
 		args = append(args, arg.String())
 	}
-	return "(call " + e.callee.String() + "(" + strings.Join(args, ",") + ")"
+	return "(call " + e.Callee.String() + "(" + strings.Join(args, ",") + ")"
 }
 
-func (e *Call) Evaluate(env *Env) (interface{}, error) {
-	callee, err := e.callee.Evaluate(env)
-	if err != nil {
-		return nil, err
-	}
-
-	var args []interface{}
-	for _, a := range e.args {
-		arg, err := a.Evaluate(env)
-		if err != nil {
-			return nil, err
-		}
-		args = append(args, arg)
-	}
+func (*Call) exprNode() {}
 
-	callable, ok := callee.(Callable)
-	if !ok {
-		return nil, &RuntimeError{
-			Msg: "can only call functions and classes",
-		}
-	}
-
-	if callable.Arity() != len(args) {
-		return nil, &RuntimeError{
-			Msg: fmt.Sprintf("expected %d arguments but got %d", callable.Arity(), len(args)),
-		}
-	}
+type GetExpr struct {
+	Object Expr
+	Name   *Token
+}
 
-	return callable.Call(env, args)
+func (e *GetExpr) Line() int {
+	return e.Name.Line
 }
 
-type Callable interface {
-	Arity() int
-	Call(env *Env, args []interface{}) (interface{}, error)
+func (e *GetExpr) String() string {
+	return "(get " + e.Object.String() + " " + e.Name.Lexeme + ")"
 }
 
-// ---
+func (*GetExpr) exprNode() {}
 
-func allNumbers(vals ...interface{}) bool {
-	for _, v := range vals {
-		if _, ok := v.(float64); !ok {
-			return false
-		}
-	}
-	return true
+// SetExpr assigns a property: object.name = value
+type SetExpr struct {
+	Object Expr
+	Name   *Token
+	Value  Expr
 }
 
-func allStrings(vals ...interface{}) bool {
-	for _, v := range vals {
-		if _, ok := v.(string); !ok {
-			return false
-		}
-	}
-	return true
-}
-func allBools(vals ...interface{}) bool {
-	for _, v := range vals {
-		if _, ok := v.(bool); !ok {
-			return false
-		}
-	}
-	return true
-}
-func isEqual(l, r interface{}) (bool, error) {
-	if allNumbers(l, r) {
-		return l.(float64) == r.(float64), nil
-	}
-	if allStrings(l, r) {
-		return l.(string) == r.(string), nil
-	}
-	if allBools(l, r) {
-		return l.(bool) == r.(bool), nil
-	}
-	return false, &RuntimeError{
-		Msg: fmt.Sprintf("cannot compare %T and %T", l, r),
-	}
+func (e *SetExpr) Line() int {
+	return e.Name.Line
 }
 
-func isTruthy(v interface{}) bool {
-	if v == nil {
-		return false
-	}
-	switch v := v.(type) {
-	case bool:
-		return v
-	default:
-		return true
-	}
+func (e *SetExpr) String() string {
+	return "(set " + e.Object.String() + " " + e.Name.Lexeme + " " + e.Value.String() + ")"
 }
+
+func (*SetExpr) exprNode() {}
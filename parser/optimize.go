@@ -0,0 +1,264 @@
+package parser
+
+import "strconv"
+
+// Optimize folds the constant expressions of stmts and removes the if
+// branches and the loops that can never run, the statements are modified
+// in place. Only operations that can't fail are folded, so runtime
+// errors are still raised when and where they used to be. Call it
+// before Resolve.
+func Optimize(stmts []Stmt) []Stmt {
+	var res []Stmt
+	for _, s := range stmts {
+		if s = optimizeStmt(s); s != nil {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// optimizeStmt returns the optimized s, nil when it does nothing
+func optimizeStmt(s Stmt) Stmt {
+	switch s := s.(type) {
+	case *PrintStmt:
+		s.Value = fold(s.Value)
+	case *ReturnStmt:
+		if s.Value != nil {
+			s.Value = fold(s.Value)
+		}
+	case *ThrowStmt:
+		s.Value = fold(s.Value)
+	case *ExprStmt:
+		s.Value = fold(s.Value)
+	case *VarDecl:
+		if s.Init != nil {
+			s.Init = fold(s.Init)
+		}
+	case *FunStmt:
+		s.Body = optimizeBranch(s.Body)
+	case *Block:
+		s.Statements = Optimize(s.Statements)
+	case *TryStmt:
+		s.Body = optimizeBranch(s.Body)
+		if s.CatchBody != nil {
+			s.CatchBody = optimizeBranch(s.CatchBody)
+		}
+		if s.FinallyBody != nil {
+			s.FinallyBody = optimizeBranch(s.FinallyBody)
+		}
+	case *IfStmt:
+		s.Expr = fold(s.Expr)
+		s.ThenBrch = optimizeBranch(s.ThenBrch)
+		if s.ElseBrch != nil {
+			s.ElseBrch = optimizeStmt(s.ElseBrch)
+		}
+		if truthy, ok := constant(s.Expr); ok {
+			if truthy {
+				return s.ThenBrch
+			}
+			return s.ElseBrch
+		}
+	case *WhileStmt:
+		s.Expr = fold(s.Expr)
+		s.Body = optimizeBranch(s.Body)
+		if truthy, ok := constant(s.Expr); ok && !truthy {
+			return nil
+		}
+	case *ForStmt:
+		s.Init = optimizeStmt(s.Init)
+		if s.Cond != nil {
+			s.Cond = fold(s.Cond)
+		}
+		if s.Incr != nil {
+			s.Incr = fold(s.Incr)
+		}
+		s.Body = optimizeBranch(s.Body)
+		if s.Cond == nil {
+			break
+		}
+		if truthy, ok := constant(s.Cond); ok && !truthy {
+			// the initializer still runs, in its own scope
+			if s.Init == nil {
+				return nil
+			}
+			return &Block{
+				Lbrace:     &Token{Typ: LEFT_BRACE, Lexeme: "{", Line: s.Keyword.Line},
+				Statements: []Stmt{s.Init},
+				Rbrace:     &Token{Typ: RIGHT_BRACE, Lexeme: "}", Line: s.Keyword.Line},
+			}
+		}
+	}
+	return s
+}
+
+// optimizeBranch optimizes a statement that can't be removed, like the
+// body of a loop, an empty block replaces it when it does nothing
+func optimizeBranch(s Stmt) Stmt {
+	line := s.Line()
+	if s = optimizeStmt(s); s != nil {
+		return s
+	}
+	return &Block{
+		Lbrace: &Token{Typ: LEFT_BRACE, Lexeme: "{", Line: line},
+		Rbrace: &Token{Typ: RIGHT_BRACE, Lexeme: "}", Line: line},
+	}
+}
+
+// fold replaces the constant parts of e by literals
+func fold(e Expr) Expr {
+	switch e := e.(type) {
+	case *GroupingExpr:
+		e.Expr = fold(e.Expr)
+		if lit, ok := e.Expr.(*LiteralExpr); ok {
+			return lit
+		}
+	case *UnaryExpr:
+		e.Right = fold(e.Right)
+		if v, ok := literalValue(e.Right); ok && e.Op.Typ == MINUS {
+			if n, ok := v.(float64); ok {
+				return literal(-n, e.Op.Line)
+			}
+		}
+	case *BinaryExpr:
+		e.Left = fold(e.Left)
+		e.Right = fold(e.Right)
+		if v, ok := foldBinary(e); ok {
+			return literal(v, e.Op.Line)
+		}
+	case *Logical:
+		e.Left = fold(e.Left)
+		e.Right = fold(e.Right)
+		// the right operand is only evaluated when the left one
+		// doesn't decide, it's the result of the expression then
+		if truthy, ok := constant(e.Left); ok {
+			if truthy == (e.Operator.Typ == OR) {
+				return e.Left
+			}
+			return e.Right
+		}
+	case *Assign:
+		e.Value = fold(e.Value)
+	case *Call:
+		e.Callee = fold(e.Callee)
+		for i, a := range e.Args {
+			e.Args[i] = fold(a)
+		}
+	case *GetExpr:
+		e.Object = fold(e.Object)
+	case *SetExpr:
+		e.Object = fold(e.Object)
+		e.Value = fold(e.Value)
+	}
+	return e
+}
+
+// foldBinary computes e when both its operands are literals and the
+// operation would succeed at runtime
+func foldBinary(e *BinaryExpr) (interface{}, bool) {
+	l, lok := literalValue(e.Left)
+	r, rok := literalValue(e.Right)
+	if !lok || !rok {
+		return nil, false
+	}
+
+	if ln, ok := l.(float64); ok {
+		if rn, ok := r.(float64); ok {
+			switch e.Op.Typ {
+			case PLUS:
+				return ln + rn, true
+			case MINUS:
+				return ln - rn, true
+			case STAR:
+				return ln * rn, true
+			case SLASH:
+				return ln / rn, true
+			case LESS:
+				return ln < rn, true
+			case LESS_EQUAL:
+				return ln <= rn, true
+			case GREATER:
+				return ln > rn, true
+			case GREATER_EQUAL:
+				return ln >= rn, true
+			case EQUAL_EQUAL:
+				return ln == rn, true
+			case BANG_EQUAL:
+				return ln != rn, true
+			}
+			return nil, false
+		}
+	}
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			switch e.Op.Typ {
+			case PLUS:
+				return ls + rs, true
+			case LESS:
+				return ls < rs, true
+			case GREATER:
+				return ls > rs, true
+			case EQUAL_EQUAL:
+				return ls == rs, true
+			case BANG_EQUAL:
+				return ls != rs, true
+			}
+			return nil, false
+		}
+	}
+	if lb, ok := l.(bool); ok {
+		if rb, ok := r.(bool); ok {
+			switch e.Op.Typ {
+			case EQUAL_EQUAL:
+				return lb == rb, true
+			case BANG_EQUAL:
+				return lb != rb, true
+			}
+		}
+	}
+	// comparing nil or different types is an error
+	return nil, false
+}
+
+// literalValue returns the value of e if it's a literal: nil, a bool,
+// a float64 or a string
+func literalValue(e Expr) (interface{}, bool) {
+	lit, ok := e.(*LiteralExpr)
+	if !ok {
+		return nil, false
+	}
+	switch lit.Value.Typ {
+	case NIL:
+		return nil, true
+	case TRUE:
+		return true, true
+	case FALSE:
+		return false, true
+	}
+	return lit.Value.Literal, true
+}
+
+// constant tells whether e is a literal and if so whether it's truthy
+func constant(e Expr) (truthy bool, ok bool) {
+	v, ok := literalValue(e)
+	if !ok {
+		return false, false
+	}
+	b, isBool := v.(bool)
+	return v != nil && (!isBool || b), true
+}
+
+func literal(v interface{}, line int) *LiteralExpr {
+	tok := &Token{Line: line}
+	switch v := v.(type) {
+	case bool:
+		tok.Typ, tok.Lexeme = FALSE, "false"
+		if v {
+			tok.Typ, tok.Lexeme = TRUE, "true"
+		}
+	case float64:
+		tok.Typ, tok.Lexeme, tok.Literal = NUMBER, strconv.FormatFloat(v, 'f', -1, 64), v
+	case string:
+		tok.Typ, tok.Lexeme, tok.Literal = STRING, `"`+v+`"`, v
+	}
+	return &LiteralExpr{Value: tok}
+}
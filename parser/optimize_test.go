@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{`print 60 * 60 * 24;`, `(print 86400)`},
+		{`print -(1 + 2) / 2;`, `(print -1.5)`},
+		{`print "a" + "b" == "ab";`, `(print true)`},
+		{`print x + 2 * 3;`, `(print (+ (value x) 6))`},
+		{`print nil or x;`, `(print (value x))`},
+		{`print true and x;`, `(print (value x))`},
+		{`print false and x;`, `(print false)`},
+		{`print 1 or x;`, `(print 1)`},
+
+		// operations failing at runtime are left alone
+		{`print 1 + "a";`, `(print (+ 1 "a"))`},
+		{`print nil == nil;`, `(print (== nil nil))`},
+		{`print -"a";`, `(print (- "a"))`},
+
+		{`if (2 > 1) print 1; else print 2;`, `(print 1)`},
+		{`if (nil) print 1; else print 2;`, `(print 2)`},
+		{`if (false) print 1;`, ``},
+		{`while (false) print 1;`, ``},
+		{`while (x) if (false) print 1;`, "(while (value x)\n(block \n)\n)"},
+		{`for (; false;) print 1;`, ``},
+		{`for (x = 1; 1 > 2; x = x + 1) print x;`, "(block \n(assign x 1)\n)"},
+		{`for (;;) print 1;`, "(for ; ; \n(print 1)\n)"},
+	}
+
+	for _, tt := range tests {
+		tokens, err := NewScanner(tt.src).Scan()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmts, err := New(tokens).Parse()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, s := range Optimize(stmts) {
+			got = append(got, s.String())
+		}
+		if strings.Join(got, "\n") != tt.expected {
+			t.Errorf("%s\ngot:      %q\nexpected: %q", tt.src, strings.Join(got, "\n"), tt.expected)
+		}
+	}
+}
@@ -34,6 +34,19 @@ func (p *Parser) Parse() ([]Stmt, error) {
 	return stmts, nil
 }
 
+// ParseExpr parses tokens holding a single expression, without the
+// semicolon of an expression statement
+func (p *Parser) ParseExpr() (Expr, error) {
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.check(EOF) {
+		return nil, p.genSyntaxError("unexpected token after expression")
+	}
+	return expr, nil
+}
+
 func (p *Parser) declaration() (Stmt, error) {
 	if p.matchAny(FUN) != nil {
 		return p.funDeclaration("function")
@@ -41,6 +54,9 @@ func (p *Parser) declaration() (Stmt, error) {
 	if p.matchAny(VAR) != nil {
 		return p.varDeclaration()
 	}
+	if kw := p.matchAny(IMPORT); kw != nil {
+		return p.importDeclaration(kw)
+	}
 	//TODO: SYNCHRONISE
 	return p.statement()
 }
@@ -78,19 +94,20 @@ func (p *Parser) funDeclaration(kind string) (Stmt, error) {
 		return nil, p.genSyntaxError("missing ')' after %v list of parameters", kind)
 	}
 
-	if p.matchAny(LEFT_BRACE) == nil {
+	lb := p.matchAny(LEFT_BRACE)
+	if lb == nil {
 		return nil, p.genSyntaxError("missing block for %v", kind)
 	}
 
-	body, err := p.blockStmt()
+	body, err := p.blockStmt(lb)
 	if err != nil {
 		return nil, err
 	}
 
 	return &FunStmt{
-		name:   name,
-		params: params,
-		body:   body,
+		Name:   name,
+		Params: params,
+		Body:   body,
 	}, nil
 }
 
@@ -113,32 +130,58 @@ func (p *Parser) varDeclaration() (Stmt, error) {
 		return nil, p.genSyntaxError("missing semicolon after value")
 	}
 
-	return &VarDecl{name: name, init: init}, nil
+	return &VarDecl{Name: name, Init: init}, nil
+}
+
+func (p *Parser) importDeclaration(keyword *Token) (Stmt, error) {
+	path := p.matchAny(STRING)
+	if path == nil {
+		return nil, p.genSyntaxError("missing module path after import")
+	}
+	// 'as' is not reserved, it can still name a variable elsewhere
+	if as := p.matchAny(IDENTIFIER); as == nil || as.Lexeme != "as" {
+		return nil, p.genSyntaxError("missing 'as' after module path")
+	}
+	name := p.matchAny(IDENTIFIER)
+	if name == nil {
+		return nil, p.genSyntaxError("missing module name after 'as'")
+	}
+	if p.matchAny(SEMICOLON) == nil {
+		return nil, p.genSyntaxError("missing semicolon after import")
+	}
+
+	return &ImportStmt{Keyword: keyword, Path: path, Name: name}, nil
 }
 
 func (p *Parser) statement() (Stmt, error) {
-	if p.matchAny(FOR) != nil {
-		return p.forStmt()
+	if kw := p.matchAny(FOR); kw != nil {
+		return p.forStmt(kw)
+	}
+	if kw := p.matchAny(IF); kw != nil {
+		return p.ifStmt(kw)
+	}
+	if kw := p.matchAny(WHILE); kw != nil {
+		return p.whileStmt(kw)
 	}
-	if p.matchAny(IF) != nil {
-		return p.ifStmt()
+	if kw := p.matchAny(PRINT); kw != nil {
+		return p.printStmt(kw)
 	}
-	if p.matchAny(WHILE) != nil {
-		return p.whileStmt()
+	if kw := p.matchAny(RETURN); kw != nil {
+		return p.returnStmt(kw)
 	}
-	if p.matchAny(PRINT) != nil {
-		return p.printStmt()
+	if kw := p.matchAny(THROW); kw != nil {
+		return p.throwStmt(kw)
 	}
-	if p.matchAny(RETURN) != nil {
-		return p.returnStmt()
+	if kw := p.matchAny(TRY); kw != nil {
+		return p.tryStmt(kw)
 	}
-	if p.matchAny(LEFT_BRACE) != nil {
-		return p.blockStmt()
+	if lb := p.matchAny(LEFT_BRACE); lb != nil {
+		return p.blockStmt(lb)
 	}
 	return p.exprStmt()
 }
 
-func (p *Parser) forStmt() (Stmt, error) {
+func (p *Parser) forStmt(keyword *Token) (Stmt, error) {
 	if p.matchAny(LEFT_PAREN) == nil {
 		return nil, p.genSyntaxError("missing '(' after for")
 	}
@@ -184,27 +227,16 @@ func (p *Parser) forStmt() (Stmt, error) {
 		return nil, err
 	}
 
-	var desugared Stmt = body
-	if incr != nil {
-		desugared = &Block{
-			statements: []Stmt{desugared, &ExprStmt{incr}},
-		}
-	}
-	if cond == nil {
-		cond = &LiteralExpr{&Token{Typ: TRUE, Literal: true}}
-	}
-	desugared = &WhileStmt{cond, desugared}
-
-	if init != nil {
-		desugared = &Block{
-			statements: []Stmt{init, desugared},
-		}
-	}
-
-	return desugared, nil
+	return &ForStmt{
+		Keyword: keyword,
+		Init:    init,
+		Cond:    cond,
+		Incr:    incr,
+		Body:    body,
+	}, nil
 }
 
-func (p *Parser) ifStmt() (Stmt, error) {
+func (p *Parser) ifStmt(keyword *Token) (Stmt, error) {
 	if p.matchAny(LEFT_PAREN) == nil {
 		return nil, p.genSyntaxError("missing '(' after if")
 	}
@@ -231,12 +263,13 @@ func (p *Parser) ifStmt() (Stmt, error) {
 	}
 
 	return &IfStmt{
-		expr:     cond,
-		thenBrch: thenBrch,
-		elseBrch: elseBrch,
+		Keyword:  keyword,
+		Expr:     cond,
+		ThenBrch: thenBrch,
+		ElseBrch: elseBrch,
 	}, nil
 }
-func (p *Parser) whileStmt() (Stmt, error) {
+func (p *Parser) whileStmt(keyword *Token) (Stmt, error) {
 	if p.matchAny(LEFT_PAREN) == nil {
 		return nil, p.genSyntaxError("missing '(' after while")
 	}
@@ -254,12 +287,13 @@ func (p *Parser) whileStmt() (Stmt, error) {
 	}
 
 	return &WhileStmt{
-		expr: cond,
-		body: body,
+		Keyword: keyword,
+		Expr:    cond,
+		Body:    body,
 	}, nil
 }
 
-func (p *Parser) printStmt() (Stmt, error) {
+func (p *Parser) printStmt(keyword *Token) (Stmt, error) {
 	exp, err := p.expression()
 	if err != nil {
 		return nil, err
@@ -267,10 +301,10 @@ func (p *Parser) printStmt() (Stmt, error) {
 	if p.matchAny(SEMICOLON) == nil {
 		return nil, p.genSyntaxError("missing semicolon after value")
 	}
-	return &PrintStmt{exp}, nil
+	return &PrintStmt{keyword, exp}, nil
 }
 
-func (p *Parser) returnStmt() (Stmt, error) {
+func (p *Parser) returnStmt(keyword *Token) (Stmt, error) {
 	var val Expr
 	if !p.check(SEMICOLON) {
 		_val, err := p.expression()
@@ -283,31 +317,94 @@ func (p *Parser) returnStmt() (Stmt, error) {
 		return nil, p.genSyntaxError("missing semicolon after return value")
 	}
 
-	return &ReturnStmt{val}, nil
+	return &ReturnStmt{Keyword: keyword, Value: val}, nil
 }
 
-func (p *Parser) blockStmt() (Stmt, error) {
-	lst, err := p.block()
+func (p *Parser) throwStmt(keyword *Token) (Stmt, error) {
+	val, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
-	return &Block{statements: lst}, nil
+	if p.matchAny(SEMICOLON) == nil {
+		return nil, p.genSyntaxError("missing semicolon after throw value")
+	}
+
+	return &ThrowStmt{Keyword: keyword, Value: val}, nil
 }
 
-func (p *Parser) block() ([]Stmt, error) {
+func (p *Parser) tryStmt(keyword *Token) (Stmt, error) {
+	lb := p.matchAny(LEFT_BRACE)
+	if lb == nil {
+		return nil, p.genSyntaxError("missing block after try")
+	}
+	body, err := p.blockStmt(lb)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &TryStmt{Keyword: keyword, Body: body}
+
+	if p.matchAny(CATCH) != nil {
+		if p.matchAny(LEFT_PAREN) == nil {
+			return nil, p.genSyntaxError("missing '(' after catch")
+		}
+		stmt.CatchName = p.matchAny(IDENTIFIER)
+		if stmt.CatchName == nil {
+			return nil, p.genSyntaxError("missing variable name in catch")
+		}
+		if p.matchAny(RIGHT_PAREN) == nil {
+			return nil, p.genSyntaxError("missing ')' after catch variable")
+		}
+		lb := p.matchAny(LEFT_BRACE)
+		if lb == nil {
+			return nil, p.genSyntaxError("missing block after catch")
+		}
+		stmt.CatchBody, err = p.blockStmt(lb)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.matchAny(FINALLY) != nil {
+		lb := p.matchAny(LEFT_BRACE)
+		if lb == nil {
+			return nil, p.genSyntaxError("missing block after finally")
+		}
+		stmt.FinallyBody, err = p.blockStmt(lb)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if stmt.CatchBody == nil && stmt.FinallyBody == nil {
+		return nil, p.genSyntaxError("missing catch or finally after try block")
+	}
+	return stmt, nil
+}
+
+func (p *Parser) blockStmt(lbrace *Token) (Stmt, error) {
+	lst, rbrace, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	return &Block{Lbrace: lbrace, Statements: lst, Rbrace: rbrace}, nil
+}
+
+func (p *Parser) block() ([]Stmt, *Token, error) {
 	var lst []Stmt
 
 	for !p.check(RIGHT_BRACE) && !p.check(EOF) {
 		stmt, err := p.declaration()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		lst = append(lst, stmt)
 	}
-	if p.matchAny(RIGHT_BRACE) == nil {
-		return nil, p.genSyntaxError("missing closing } after block")
+	rbrace := p.matchAny(RIGHT_BRACE)
+	if rbrace == nil {
+		return nil, nil, p.genSyntaxError("missing closing } after block")
 	}
-	return lst, nil
+	return lst, rbrace, nil
 }
 
 func (p *Parser) exprStmt() (Stmt, error) {
@@ -336,10 +433,17 @@ func (p *Parser) assignment() (Expr, error) {
 			return nil, err
 		}
 
-		if v, ok := expr.(*Variable); ok {
+		switch e := expr.(type) {
+		case *Variable:
 			return &Assign{
-				name:  v.name,
-				value: val,
+				Name:  e.Name,
+				Value: val,
+			}, nil
+		case *GetExpr:
+			return &SetExpr{
+				Object: e.Object,
+				Name:   e.Name,
+				Value:  val,
 			}, nil
 		}
 		return nil, p.genSyntaxError("invalid assignment target")
@@ -361,9 +465,9 @@ func (p *Parser) or() (Expr, error) {
 				return nil, err
 			}
 			expr = &Logical{
-				left:     expr,
-				operator: op,
-				right:    right,
+				Left:     expr,
+				Operator: op,
+				Right:    right,
 			}
 		}
 	}
@@ -384,9 +488,9 @@ func (p *Parser) and() (Expr, error) {
 				return nil, err
 			}
 			expr = &Logical{
-				left:     expr,
-				operator: op,
-				right:    right,
+				Left:     expr,
+				Operator: op,
+				Right:    right,
 			}
 		}
 	}
@@ -428,6 +532,12 @@ func (p *Parser) call() (Expr, error) {
 				return nil, err
 			}
 			expr = _expr
+		} else if p.matchAny(DOT) != nil {
+			name := p.matchAny(IDENTIFIER)
+			if name == nil {
+				return nil, p.genSyntaxError("missing property name after '.'")
+			}
+			expr = &GetExpr{Object: expr, Name: name}
 		} else {
 			break
 		}
@@ -461,9 +571,9 @@ func (p *Parser) finishCall(callee Expr) (Expr, error) {
 	}
 
 	return &Call{
-		callee: callee,
-		paren:  rp,
-		args:   args,
+		Callee: callee,
+		Paren:  rp,
+		Args:   args,
 	}, nil
 }
 
@@ -472,7 +582,7 @@ func (p *Parser) primary() (Expr, error) {
 		return &LiteralExpr{tok}, nil
 	}
 	if name := p.matchAny(IDENTIFIER); name != nil {
-		return &Variable{name}, nil
+		return &Variable{Name: name}, nil
 	}
 	if lp := p.matchAny(LEFT_PAREN); lp != nil {
 		expr, err := p.expression()
@@ -0,0 +1,200 @@
+package parser
+
+// Scope lists the local variables declared in a block, a function's
+// parameters, a for loop initializer or a catch clause. At runtime
+// each scope is a slice of values indexed like Names.
+type Scope struct {
+	Names []string
+}
+
+// Slot locates a local variable: Depth scopes up from the current one,
+// at Index in that scope
+type Slot struct {
+	Depth int
+	Index int
+}
+
+// Resolve binds every local variable of stmts to its slot. Variables
+// left unresolved are globals, looked up by name when executed.
+//
+// Functions only see their own locals and the globals, so resolution
+// starts afresh in each function body. Blocks declaring nothing get no
+// scope at all.
+func Resolve(stmts []Stmt) {
+	r := &resolver{}
+	for _, s := range stmts {
+		r.stmt(s)
+	}
+}
+
+// ResolveExpr binds the variables of e named in scope to their slot in
+// it, the others are globals. Unlike statements, an expression can't
+// declare anything so scope isn't modified.
+func ResolveExpr(e Expr, scope *Scope) {
+	b := &scopeBuilder{scope: scope, index: make(map[string]int)}
+	for idx, name := range scope.Names {
+		b.index[name] = idx
+	}
+	r := &resolver{scopes: []*scopeBuilder{b}}
+	r.expr(e)
+}
+
+type resolver struct {
+	// innermost scope last, empty at the top level
+	scopes []*scopeBuilder
+	// try statements around the current one, in the current function
+	tries int
+	// in a function body, a return at the top level is never a tail call
+	inFun bool
+}
+
+type scopeBuilder struct {
+	scope *Scope
+	index map[string]int
+}
+
+func (r *resolver) push() *Scope {
+	b := &scopeBuilder{scope: &Scope{}, index: make(map[string]int)}
+	r.scopes = append(r.scopes, b)
+	return b.scope
+}
+
+func (r *resolver) pop() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// declare adds name to the current scope, redeclaring a name reuses
+// its slot. It returns nil at the top level.
+func (r *resolver) declare(name *Token) *Slot {
+	if len(r.scopes) == 0 {
+		return nil
+	}
+	b := r.scopes[len(r.scopes)-1]
+	idx, ok := b.index[name.Lexeme]
+	if !ok {
+		idx = len(b.scope.Names)
+		b.index[name.Lexeme] = idx
+		b.scope.Names = append(b.scope.Names, name.Lexeme)
+	}
+	return &Slot{Depth: 0, Index: idx}
+}
+
+func (r *resolver) resolve(name *Token) *Slot {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if idx, ok := r.scopes[i].index[name.Lexeme]; ok {
+			return &Slot{Depth: len(r.scopes) - 1 - i, Index: idx}
+		}
+	}
+	return nil
+}
+
+// declares tells whether stmts declare anything in their own scope
+func declares(stmts []Stmt) bool {
+	for _, s := range stmts {
+		switch s.(type) {
+		case *VarDecl, *FunStmt, *ImportStmt:
+			return true
+		}
+	}
+	return false
+}
+
+func (r *resolver) stmt(s Stmt) {
+	switch s := s.(type) {
+	case *PrintStmt:
+		r.expr(s.Value)
+	case *ReturnStmt:
+		if s.Value != nil {
+			r.expr(s.Value)
+		}
+		_, isCall := s.Value.(*Call)
+		s.Tail = isCall && r.inFun && r.tries == 0
+	case *ThrowStmt:
+		r.expr(s.Value)
+	case *ExprStmt:
+		r.expr(s.Value)
+	case *VarDecl:
+		// the initializer doesn't see the variable being declared
+		if s.Init != nil {
+			r.expr(s.Init)
+		}
+		s.Local = r.declare(s.Name)
+	case *ImportStmt:
+		s.Local = r.declare(s.Name)
+	case *FunStmt:
+		s.Local = r.declare(s.Name)
+
+		outer, tries, inFun := r.scopes, r.tries, r.inFun
+		r.scopes, r.tries, r.inFun = nil, 0, true
+		s.Scope = r.push()
+		for _, p := range s.Params {
+			r.declare(p)
+		}
+		r.stmt(s.Body)
+		r.scopes, r.tries, r.inFun = outer, tries, inFun
+	case *Block:
+		if declares(s.Statements) {
+			s.Scope = r.push()
+			defer r.pop()
+		}
+		for _, st := range s.Statements {
+			r.stmt(st)
+		}
+	case *IfStmt:
+		r.expr(s.Expr)
+		r.stmt(s.ThenBrch)
+		if s.ElseBrch != nil {
+			r.stmt(s.ElseBrch)
+		}
+	case *WhileStmt:
+		r.expr(s.Expr)
+		r.stmt(s.Body)
+	case *ForStmt:
+		if s.Init != nil && declares([]Stmt{s.Init}) {
+			s.Scope = r.push()
+			defer r.pop()
+		}
+		if s.Init != nil {
+			r.stmt(s.Init)
+		}
+		if s.Cond != nil {
+			r.expr(s.Cond)
+		}
+		if s.Incr != nil {
+			r.expr(s.Incr)
+		}
+		r.stmt(s.Body)
+	case *TryStmt:
+		// the catch clause must see the errors of a call returned
+		// from the body, and finally must run after it
+		r.tries++
+		r.stmt(s.Body)
+		if s.FinallyBody == nil {
+			r.tries--
+		}
+		if s.CatchBody != nil {
+			s.CatchScope = r.push()
+			r.declare(s.CatchName)
+			r.stmt(s.CatchBody)
+			r.pop()
+		}
+		if s.FinallyBody != nil {
+			r.tries--
+		}
+		if s.FinallyBody != nil {
+			r.stmt(s.FinallyBody)
+		}
+	}
+}
+
+func (r *resolver) expr(e Expr) {
+	Inspect(e, func(n Node) bool {
+		switch n := n.(type) {
+		case *Variable:
+			n.Local = r.resolve(n.Name)
+		case *Assign:
+			n.Local = r.resolve(n.Name)
+		}
+		return true
+	})
+}
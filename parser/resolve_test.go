@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tokens, err := NewScanner(`
+		var g = 1;
+		fun f(a, b) {
+			var c = a;
+			{
+				var a = b;
+				c = a + g;
+			}
+			return c;
+		}
+	`).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := New(tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Resolve(stmts)
+
+	fun := stmts[1].(*FunStmt)
+	if got := fun.Scope.Names; !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("parameters scope: got %v", got)
+	}
+	if got := fun.Body.(*Block).Scope.Names; !reflect.DeepEqual(got, []string{"c"}) {
+		t.Errorf("body scope: got %v", got)
+	}
+
+	var resolved []string
+	Inspect(fun, func(n Node) bool {
+		var name *Token
+		var slot *Slot
+		switch n := n.(type) {
+		case *Variable:
+			name, slot = n.Name, n.Local
+		case *Assign:
+			name, slot = n.Name, n.Local
+		default:
+			return true
+		}
+		if slot == nil {
+			resolved = append(resolved, name.Lexeme+":global")
+		} else {
+			resolved = append(resolved, fmt.Sprintf("%s:%d/%d", name.Lexeme, slot.Depth, slot.Index))
+		}
+		return true
+	})
+
+	expected := []string{
+		"a:1/0", // var c = a;
+		"b:2/1", // var a = b;
+		"c:1/0", // c = a + g;
+		"a:0/0",
+		"g:global",
+		"c:0/0", // return c;
+	}
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("got %v\nexpected %v", resolved, expected)
+	}
+}
+
+func TestResolveTailCalls(t *testing.T) {
+	tokens, err := NewScanner(`
+		fun f(n) {
+			if (n) return f(n - 1);
+			try {
+				return f(n);
+			} catch (e) {
+				return f(n);
+			}
+			try {
+				return f(n);
+			} finally {
+				return f(n);
+			}
+			fun g() { return f(1); }
+			return 1 + f(n);
+		}
+		return f(1);
+	`).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := New(tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	Resolve(stmts)
+
+	var tail []int
+	for _, s := range stmts {
+		Inspect(s, func(n Node) bool {
+			if r, ok := n.(*ReturnStmt); ok && r.Tail {
+				tail = append(tail, r.Line())
+			}
+			return true
+		})
+	}
+	// not in try bodies, nor in a catch clause followed by finally,
+	// nor at the top level
+	expected := []int{3, 7, 12, 14}
+	if !reflect.DeepEqual(tail, expected) {
+		t.Errorf("tail calls on lines %v, expected %v", tail, expected)
+	}
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 type Scanner struct {
@@ -10,6 +11,22 @@ type Scanner struct {
 	line    int
 	start   int
 	current int
+
+	// comments are only recorded by ScanWithComments, the interpreter
+	// doesn't need them
+	keepComments bool
+	comments     []*Comment
+	// line of the last token produced
+	tokenLine int
+}
+
+// Comment is a // comment skipped by the scanner, tools like the
+// formatter use them to print the comments back
+type Comment struct {
+	Text string
+	Line int
+	// Trailing is true when the comment follows a token on the same line
+	Trailing bool
 }
 
 type ScanningError struct {
@@ -31,7 +48,29 @@ func NewScanner(input string) *Scanner {
 }
 
 func (s *Scanner) Scan() ([]*Token, error) {
+	return s.scan(false)
+}
+
+// ScanWithComments works like Scan and also records the comments,
+// returned by Comments afterwards
+func (s *Scanner) ScanWithComments() ([]*Token, error) {
+	s.keepComments = true
+	return s.scan(false)
+}
+
+// ScanWithTrivia works like Scan but also attaches to each token the
+// whitespace, newlines and comments around it. A token's trailing trivia
+// runs until the end of its line, everything else before a token is its
+// leading trivia. Concatenating Leading, Lexeme and Trailing of every
+// token gives back the input.
+func (s *Scanner) ScanWithTrivia() ([]*Token, error) {
+	return s.scan(true)
+}
+
+func (s *Scanner) scan(trivia bool) ([]*Token, error) {
 	var tokens []*Token
+	// start of the trivia not attached to any token yet
+	triviaStart := 0
 
 	for !s.eof() {
 		s.start = s.current
@@ -40,14 +79,45 @@ func (s *Scanner) Scan() ([]*Token, error) {
 			return nil, err
 		}
 		if tok != nil {
+			if trivia {
+				attachTrivia(tokens, tok, s.input[triviaStart:s.start])
+				triviaStart = s.current
+			}
 			tokens = append(tokens, tok)
+			s.tokenLine = tok.Line
 		}
 	}
 
-	tokens = append(tokens, &Token{EOF, "", nil, s.line})
+	eof := &Token{Typ: EOF, Line: s.line}
+	if trivia {
+		attachTrivia(tokens, eof, s.input[triviaStart:])
+	}
+	tokens = append(tokens, eof)
 	return tokens, nil
 }
 
+// attachTrivia splits the trivia found between the last token of tokens
+// and tok, the part on the same line as the last token trails it
+func attachTrivia(tokens []*Token, tok *Token, trivia string) {
+	if len(tokens) > 0 {
+		prev := tokens[len(tokens)-1]
+		idx := strings.IndexByte(trivia, '\n')
+		if idx < 0 {
+			prev.Trailing = trivia
+			return
+		}
+		prev.Trailing = trivia[:idx]
+		trivia = trivia[idx:]
+	}
+	tok.Leading = trivia
+}
+
+// Comments returns the comments found by ScanWithComments, in source
+// order
+func (s *Scanner) Comments() []*Comment {
+	return s.comments
+}
+
 func (s *Scanner) scanToken() (*Token, error) {
 	c := s.advance()
 	switch c {
@@ -77,6 +147,13 @@ func (s *Scanner) scanToken() (*Token, error) {
 			for s.peek() != '\n' && !s.eof() {
 				s.advance()
 			}
+			if s.keepComments {
+				s.comments = append(s.comments, &Comment{
+					Text:     s.input[s.start:s.current],
+					Line:     s.line,
+					Trailing: s.tokenLine == s.line,
+				})
+			}
 			return nil, nil
 		}
 		return s.genToken(SLASH, nil)
@@ -172,7 +249,7 @@ func (s *Scanner) genIdent() (*Token, error) {
 
 func (s *Scanner) genToken(typ TokenType, val interface{}) (*Token, error) {
 	lex := s.input[s.start:s.current]
-	return &Token{typ, lex, val, s.line}, nil
+	return &Token{Typ: typ, Lexeme: lex, Literal: val, Line: s.line}, nil
 }
 
 func (s *Scanner) genError(format string, v ...interface{}) (*Token, error) {
@@ -220,7 +297,7 @@ func isDigit(c rune) bool {
 	return c >= '0' && c <= '9'
 }
 func isAlpha(c rune) bool {
-	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
 }
 func isAlphaNum(c rune) bool {
 	return isDigit(c) || isAlpha(c)
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanWithTriviaIsLossless(t *testing.T) {
+	inputs := []string{
+		"",
+		"   ",
+		"print 1;",
+		"  var a = 1;   // trailing\n\n// own line\n\tprint a ;\n",
+		"fun f() {\n  return \"multi\nline\";\n}\n// last comment",
+		"print 1;\r\n",
+	}
+	files, err := filepath.Glob("../interpreter/testdata/*/*.lox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, string(b))
+	}
+
+	for _, input := range inputs {
+		tokens, err := NewScanner(input).ScanWithTrivia()
+		if err != nil {
+			// some test files are invalid on purpose
+			continue
+		}
+
+		var b strings.Builder
+		for _, tok := range tokens {
+			b.WriteString(tok.Leading + tok.Lexeme + tok.Trailing)
+		}
+		if b.String() != input {
+			t.Errorf("input not reproduced\ngot:      %q\nexpected: %q", b.String(), input)
+		}
+	}
+}
+
+func TestScanWithTrivia(t *testing.T) {
+	tokens, err := NewScanner("var a = 1; // one\n  // two\nprint a;").ScanWithTrivia()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	semi := tokens[4]
+	if semi.Lexeme != ";" || semi.Trailing != " // one" {
+		t.Errorf("unexpected trailing trivia for %v: %q", semi, semi.Trailing)
+	}
+	printTok := tokens[5]
+	if printTok.Typ != PRINT || printTok.Leading != "\n  // two\n" {
+		t.Errorf("unexpected leading trivia for %v: %q", printTok, printTok.Leading)
+	}
+}
+
+func TestScanHasNoTrivia(t *testing.T) {
+	tokens, err := NewScanner("var a = 1; // one\nprint a;").Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tok := range tokens {
+		if tok.Leading != "" || tok.Trailing != "" {
+			t.Errorf("unexpected trivia on %v", tok)
+		}
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	src, err := os.ReadFile("../format/testdata/messy.lox")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		if _, err := NewScanner(string(src)).Scan(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestScanComments(t *testing.T) {
+	src := "var a = 1; // one\n  // two\nprint a;"
+	s := NewScanner(src)
+	if _, err := s.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Comments()) != 0 {
+		t.Errorf("Scan recorded comments %v", s.Comments())
+	}
+
+	s = NewScanner(src)
+	if _, err := s.ScanWithComments(); err != nil {
+		t.Fatal(err)
+	}
+	var got []Comment
+	for _, c := range s.Comments() {
+		got = append(got, *c)
+	}
+	expected := []Comment{
+		{Text: "// one", Line: 1, Trailing: true},
+		{Text: "// two", Line: 2},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got comments %v, expected %v", got, expected)
+	}
+}
+
+// comments only cost something when they are asked for
+func BenchmarkScanComments(b *testing.B) {
+	src := strings.Repeat("// a comment on its own line\nvar a = 1; // trailing\n", 500)
+	b.Run("Scan", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewScanner(src).Scan(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("ScanWithComments", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewScanner(src).ScanWithComments(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -6,198 +6,315 @@ import (
 )
 
 type Stmt interface {
-	Evaluate(env *Env) error
-	String() string
+	Node
+	stmtNode()
+}
+
+// StmtVisitor is implemented by back ends executing statements with
+// results of type R, VisitStmt dispatches each node to its Visit method
+type StmtVisitor[R any] interface {
+	VisitPrintStmt(e *PrintStmt) (R, error)
+	VisitReturnStmt(e *ReturnStmt) (R, error)
+	VisitThrowStmt(e *ThrowStmt) (R, error)
+	VisitTryStmt(e *TryStmt) (R, error)
+	VisitExprStmt(e *ExprStmt) (R, error)
+	VisitFunStmt(e *FunStmt) (R, error)
+	VisitImportStmt(e *ImportStmt) (R, error)
+	VisitVarDecl(e *VarDecl) (R, error)
+	VisitBlock(e *Block) (R, error)
+	VisitIfStmt(e *IfStmt) (R, error)
+	VisitWhileStmt(e *WhileStmt) (R, error)
+	VisitForStmt(e *ForStmt) (R, error)
+}
+
+// VisitStmt calls the Visit method of v for s
+func VisitStmt[R any](s Stmt, v StmtVisitor[R]) (R, error) {
+	switch s := s.(type) {
+	case *PrintStmt:
+		return v.VisitPrintStmt(s)
+	case *ReturnStmt:
+		return v.VisitReturnStmt(s)
+	case *ThrowStmt:
+		return v.VisitThrowStmt(s)
+	case *TryStmt:
+		return v.VisitTryStmt(s)
+	case *ExprStmt:
+		return v.VisitExprStmt(s)
+	case *FunStmt:
+		return v.VisitFunStmt(s)
+	case *ImportStmt:
+		return v.VisitImportStmt(s)
+	case *VarDecl:
+		return v.VisitVarDecl(s)
+	case *Block:
+		return v.VisitBlock(s)
+	case *IfStmt:
+		return v.VisitIfStmt(s)
+	case *WhileStmt:
+		return v.VisitWhileStmt(s)
+	case *ForStmt:
+		return v.VisitForStmt(s)
+	}
+	panic(fmt.Sprintf("parser: unexpected statement %T", s))
 }
 
 type PrintStmt struct {
-	value Expr
+	Keyword *Token
+	Value   Expr
+}
+
+func (e *PrintStmt) Line() int {
+	return e.Keyword.Line
 }
 
 func (e *PrintStmt) String() string {
-	return "(print " + e.value.String() + ")"
+	return "(print " + e.Value.String() + ")"
 }
 
-func (e *PrintStmt) Evaluate(env *Env) error {
-	v, err := e.value.Evaluate(env)
-	if err != nil {
-		return err
+func (*PrintStmt) stmtNode() {}
+
+type ReturnStmt struct {
+	Keyword *Token
+	Value   Expr
+	// set by Resolve when Value is a call that can be made after
+	// leaving the function, there's no try statement to go through
+	Tail bool
+}
+
+func (e *ReturnStmt) Line() int {
+	return e.Keyword.Line
+}
+
+func (e *ReturnStmt) String() string {
+	if e.Value == nil {
+		return "(return)"
 	}
-	fmt.Println(v)
-	return nil
+	return "(return " + e.Value.String() + ")"
+}
+
+func (*ReturnStmt) stmtNode() {}
+
+type ThrowStmt struct {
+	Keyword *Token
+	Value   Expr
 }
 
-// we define it as an error so it can bubble up like an exception
-// could use panic instead but that seemed overkill
-type ReturnValue struct {
-	val interface{}
+func (e *ThrowStmt) Line() int {
+	return e.Keyword.Line
 }
 
-func (r *ReturnValue) Error() string {
-	return ""
+func (e *ThrowStmt) String() string {
+	return "(throw " + e.Value.String() + ")"
 }
 
-type ReturnStmt struct {
-	value Expr
+func (*ThrowStmt) stmtNode() {}
+
+type TryStmt struct {
+	Keyword     *Token
+	Body        Stmt
+	CatchName   *Token
+	CatchBody   Stmt
+	FinallyBody Stmt
+	// scope of the caught value, set by Resolve
+	CatchScope *Scope
 }
 
-func (e *ReturnStmt) String() string {
-	return "(return " + e.value.String() + ")"
+func (e *TryStmt) Line() int {
+	return e.Keyword.Line
 }
 
-func (e *ReturnStmt) Evaluate(env *Env) error {
-	if e.value == nil {
-		return &ReturnValue{nil}
+func (e *TryStmt) String() string {
+	var b strings.Builder
+	b.WriteString("(try\n")
+	b.WriteString(e.Body.String() + "\n")
+	if e.CatchBody != nil {
+		b.WriteString(") catch " + e.CatchName.Lexeme + " (\n")
+		b.WriteString(e.CatchBody.String() + "\n")
 	}
-
-	v, err := e.value.Evaluate(env)
-	if err != nil {
-		return err
+	if e.FinallyBody != nil {
+		b.WriteString(") finally (\n")
+		b.WriteString(e.FinallyBody.String() + "\n")
 	}
-	return &ReturnValue{v}
+	b.WriteString(")")
+	return b.String()
 }
 
+func (*TryStmt) stmtNode() {}
+
 type ExprStmt struct {
-	value Expr
+	Value Expr
 }
 
-func (e *ExprStmt) String() string {
-	return e.value.String()
+func (e *ExprStmt) Line() int {
+	return e.Value.Line()
 }
 
-func (e *ExprStmt) Evaluate(env *Env) error {
-	_, err := e.value.Evaluate(env)
-	return err
+func (e *ExprStmt) String() string {
+	return e.Value.String()
 }
 
+func (*ExprStmt) stmtNode() {}
+
 type FunStmt struct {
-	name   *Token
-	params []*Token
-	body   Stmt
+	Name   *Token
+	Params []*Token
+	Body   Stmt
+	// set by Resolve, Local is nil for global functions and Scope
+	// holds the parameters
+	Local *Slot
+	Scope *Scope
+}
+
+func (e *FunStmt) Line() int {
+	return e.Name.Line
 }
 
 func (e *FunStmt) String() string {
-	return e.name.String()
+	return e.Name.String()
 }
 
-func (e *FunStmt) Evaluate(env *Env) error {
-	env.Define(e.name.Lexeme, &LoxFunction{
-		Declaration: e,
-	})
-	return nil
+func (*FunStmt) stmtNode() {}
+
+type ImportStmt struct {
+	Keyword *Token
+	Path    *Token
+	Name    *Token
+	// set by Resolve for imports inside a block, nil at the top level
+	Local *Slot
+}
+
+func (e *ImportStmt) Line() int {
+	return e.Keyword.Line
+}
+
+func (e *ImportStmt) String() string {
+	return "(import " + e.Path.Lexeme + " as " + e.Name.Lexeme + ")"
 }
 
+func (*ImportStmt) stmtNode() {}
+
 type VarDecl struct {
-	name *Token
-	init Expr
+	Name *Token
+	Init Expr
+	// set by Resolve for local variables, nil for globals
+	Local *Slot
 }
 
-func (e *VarDecl) String() string {
-	if e.init == nil {
-		return "(var " + e.name.String() + " )"
-	}
-	return "(var " + e.name.String() + " = " + e.init.String() + " )"
+func (e *VarDecl) Line() int {
+	return e.Name.Line
 }
 
-func (e *VarDecl) Evaluate(env *Env) error {
-	var init interface{}
-	if e.init != nil {
-		v, err := e.init.Evaluate(env)
-		if err != nil {
-			return err
-		}
-		init = v
+func (e *VarDecl) String() string {
+	if e.Init == nil {
+		return "(var " + e.Name.String() + " )"
 	}
-	env.Define(e.name.Lexeme, init)
-	return nil
+	return "(var " + e.Name.String() + " = " + e.Init.String() + " )"
 }
 
+func (*VarDecl) stmtNode() {}
+
 type Block struct {
-	statements []Stmt
+	Lbrace     *Token
+	Statements []Stmt
+	Rbrace     *Token
+	// set by Resolve, nil when the block declares nothing
+	Scope *Scope
+}
+
+func (e *Block) Line() int {
+	return e.Lbrace.Line
 }
 
 func (e *Block) String() string {
 	var b strings.Builder
 	b.WriteString("(block \n")
-	for _, s := range e.statements {
+	for _, s := range e.Statements {
 		b.WriteString(s.String() + "\n")
 	}
 	b.WriteString(")")
 	return b.String()
 }
 
-func (e *Block) Evaluate(env *Env) error {
-	scope := NewEnv(env)
+func (*Block) stmtNode() {}
 
-	for _, s := range e.statements {
-		if err := s.Evaluate(scope); err != nil {
-			return err
-		}
-	}
-
-	return nil
+type IfStmt struct {
+	Keyword  *Token
+	Expr     Expr
+	ThenBrch Stmt
+	ElseBrch Stmt
 }
 
-type IfStmt struct {
-	expr     Expr
-	thenBrch Stmt
-	elseBrch Stmt
+func (e *IfStmt) Line() int {
+	return e.Keyword.Line
 }
 
 func (e *IfStmt) String() string {
 	var b strings.Builder
-	b.WriteString("(if " + e.expr.String() + "\n")
-	b.WriteString(e.thenBrch.String() + "\n")
-	if e.elseBrch != nil {
+	b.WriteString("(if " + e.Expr.String() + "\n")
+	b.WriteString(e.ThenBrch.String() + "\n")
+	if e.ElseBrch != nil {
 		b.WriteString(") else (\n")
-		b.WriteString(e.elseBrch.String() + "\n")
+		b.WriteString(e.ElseBrch.String() + "\n")
 	}
 	b.WriteString(")")
 	return b.String()
 }
 
-func (e *IfStmt) Evaluate(env *Env) error {
-
-	val, err := e.expr.Evaluate(env)
-	if err != nil {
-		return err
-	}
+func (*IfStmt) stmtNode() {}
 
-	if isTruthy(val) {
-		return e.thenBrch.Evaluate(env)
-	} else {
-		if e.elseBrch != nil {
-			return e.elseBrch.Evaluate(env)
-		}
-	}
-	return nil
+type WhileStmt struct {
+	Keyword *Token
+	Expr    Expr
+	Body    Stmt
 }
 
-type WhileStmt struct {
-	expr Expr
-	body Stmt
+func (e *WhileStmt) Line() int {
+	return e.Keyword.Line
 }
 
 func (e *WhileStmt) String() string {
 	var b strings.Builder
-	b.WriteString("(while " + e.expr.String() + "\n")
-	b.WriteString(e.body.String() + "\n")
+	b.WriteString("(while " + e.Expr.String() + "\n")
+	b.WriteString(e.Body.String() + "\n")
 	b.WriteString(")")
 	return b.String()
 }
 
-func (e *WhileStmt) Evaluate(env *Env) error {
-	for {
-		cond, err := e.expr.Evaluate(env)
-		if err != nil {
-			return err
-		}
-		if !isTruthy(cond) {
-			return nil
-		}
+func (*WhileStmt) stmtNode() {}
+
+// ForStmt is kept as written rather than desugared into a while loop,
+// so tools can print it back. Init, Cond and Incr are optional.
+type ForStmt struct {
+	Keyword *Token
+	Init    Stmt
+	Cond    Expr
+	Incr    Expr
+	Body    Stmt
+	// set by Resolve, nil unless Init declares a variable
+	Scope *Scope
+}
 
-		if err := e.body.Evaluate(env); err != nil {
-			return err
-		}
+func (e *ForStmt) Line() int {
+	return e.Keyword.Line
+}
+
+func (e *ForStmt) String() string {
+	var b strings.Builder
+	b.WriteString("(for ")
+	if e.Init != nil {
+		b.WriteString(e.Init.String())
+	}
+	b.WriteString("; ")
+	if e.Cond != nil {
+		b.WriteString(e.Cond.String())
+	}
+	b.WriteString("; ")
+	if e.Incr != nil {
+		b.WriteString(e.Incr.String())
 	}
+	b.WriteString("\n" + e.Body.String() + "\n")
+	b.WriteString(")")
+	return b.String()
 }
+
+func (*ForStmt) stmtNode() {}
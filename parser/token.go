@@ -2,13 +2,21 @@
 
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
 type Token struct {
 	Typ     TokenType
 	Lexeme  string
 	Literal interface{}
 	Line    int
+
+	// whitespace, newlines and comments around the token, only filled
+	// by Scanner.ScanWithTrivia
+	Leading  string
+	Trailing string
 }
 
 func (t Token) String() string {
@@ -44,19 +52,24 @@ const (
 	NUMBER
 
 	AND
+	CATCH
 	CLASS
 	ELSE
 	FALSE
+	FINALLY
 	FUN
 	FOR
 	IF
+	IMPORT
 	NIL
 	OR
 	PRINT
 	RETURN
 	SUPER
 	THIS
+	THROW
 	TRUE
+	TRY
 	VAR
 	WHILE
 
@@ -64,20 +77,35 @@ const (
 )
 
 var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"fun":    FUN,
-	"for":    FOR,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
+	"and":     AND,
+	"catch":   CATCH,
+	"class":   CLASS,
+	"else":    ELSE,
+	"false":   FALSE,
+	"finally": FINALLY,
+	"fun":     FUN,
+	"for":     FOR,
+	"if":      IF,
+	"import":  IMPORT,
+	"nil":     NIL,
+	"or":      OR,
+	"print":   PRINT,
+	"return":  RETURN,
+	"super":   SUPER,
+	"this":    THIS,
+	"throw":   THROW,
+	"true":    TRUE,
+	"try":     TRY,
+	"var":     VAR,
+	"while":   WHILE,
+}
+
+// Keywords returns the reserved words of the language, sorted
+func Keywords() []string {
+	var kw []string
+	for k := range keywords {
+		kw = append(kw, k)
+	}
+	sort.Strings(kw)
+	return kw
 }
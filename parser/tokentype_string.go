@@ -31,27 +31,32 @@ func _() {
 	_ = x[STRING-20]
 	_ = x[NUMBER-21]
 	_ = x[AND-22]
-	_ = x[CLASS-23]
-	_ = x[ELSE-24]
-	_ = x[FALSE-25]
-	_ = x[FUN-26]
-	_ = x[FOR-27]
-	_ = x[IF-28]
-	_ = x[NIL-29]
-	_ = x[OR-30]
-	_ = x[PRINT-31]
-	_ = x[RETURN-32]
-	_ = x[SUPER-33]
-	_ = x[THIS-34]
-	_ = x[TRUE-35]
-	_ = x[VAR-36]
-	_ = x[WHILE-37]
-	_ = x[EOF-38]
+	_ = x[CATCH-23]
+	_ = x[CLASS-24]
+	_ = x[ELSE-25]
+	_ = x[FALSE-26]
+	_ = x[FINALLY-27]
+	_ = x[FUN-28]
+	_ = x[FOR-29]
+	_ = x[IF-30]
+	_ = x[IMPORT-31]
+	_ = x[NIL-32]
+	_ = x[OR-33]
+	_ = x[PRINT-34]
+	_ = x[RETURN-35]
+	_ = x[SUPER-36]
+	_ = x[THIS-37]
+	_ = x[THROW-38]
+	_ = x[TRUE-39]
+	_ = x[TRY-40]
+	_ = x[VAR-41]
+	_ = x[WHILE-42]
+	_ = x[EOF-43]
 }
 
-const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGNUMBERANDCLASSELSEFALSEFUNFORIFNILORPRINTRETURNSUPERTHISTRUEVARWHILEEOF"
+const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGNUMBERANDCATCHCLASSELSEFALSEFINALLYFUNFORIFIMPORTNILORPRINTRETURNSUPERTHISTHROWTRUETRYVARWHILEEOF"
 
-var _TokenType_index = [...]uint8{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 81, 91, 96, 107, 114, 127, 131, 141, 151, 157, 163, 166, 171, 175, 180, 183, 186, 188, 191, 193, 198, 204, 209, 213, 217, 220, 225, 228}
+var _TokenType_index = [...]uint16{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 81, 91, 96, 107, 114, 127, 131, 141, 151, 157, 163, 166, 171, 176, 180, 185, 192, 195, 198, 200, 206, 209, 211, 216, 222, 227, 231, 236, 240, 243, 246, 251, 254}
 
 func (i TokenType) String() string {
 	if i < 0 || i >= TokenType(len(_TokenType_index)-1) {
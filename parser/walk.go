@@ -0,0 +1,130 @@
+package parser
+
+import "fmt"
+
+// Node is implemented by every Expr and Stmt of the syntax tree
+type Node interface {
+	// Line returns the line where the node starts
+	Line() int
+	String() string
+}
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses a syntax tree in depth-first order: it starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w
+// for each of the non-nil children of node, followed by a call of
+// w.Visit(nil).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	// expressions
+	case *BinaryExpr:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *UnaryExpr:
+		Walk(v, n.Right)
+	case *LiteralExpr, *Variable:
+		// nothing to do
+	case *GroupingExpr:
+		Walk(v, n.Expr)
+	case *Assign:
+		Walk(v, n.Value)
+	case *Logical:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *Call:
+		Walk(v, n.Callee)
+		for _, a := range n.Args {
+			Walk(v, a)
+		}
+	case *GetExpr:
+		Walk(v, n.Object)
+	case *SetExpr:
+		Walk(v, n.Object)
+		Walk(v, n.Value)
+
+	// statements
+	case *PrintStmt:
+		Walk(v, n.Value)
+	case *ReturnStmt:
+		if n.Value != nil {
+			Walk(v, n.Value)
+		}
+	case *ThrowStmt:
+		Walk(v, n.Value)
+	case *TryStmt:
+		Walk(v, n.Body)
+		if n.CatchBody != nil {
+			Walk(v, n.CatchBody)
+		}
+		if n.FinallyBody != nil {
+			Walk(v, n.FinallyBody)
+		}
+	case *ExprStmt:
+		Walk(v, n.Value)
+	case *FunStmt:
+		Walk(v, n.Body)
+	case *ImportStmt:
+		// nothing to do
+	case *VarDecl:
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+	case *Block:
+		for _, s := range n.Statements {
+			Walk(v, s)
+		}
+	case *IfStmt:
+		Walk(v, n.Expr)
+		Walk(v, n.ThenBrch)
+		if n.ElseBrch != nil {
+			Walk(v, n.ElseBrch)
+		}
+	case *WhileStmt:
+		Walk(v, n.Expr)
+		Walk(v, n.Body)
+	case *ForStmt:
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+		if n.Cond != nil {
+			Walk(v, n.Cond)
+		}
+		if n.Incr != nil {
+			Walk(v, n.Incr)
+		}
+		Walk(v, n.Body)
+
+	default:
+		panic(fmt.Sprintf("parser.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a syntax tree in depth-first order: it starts by
+// calling f(node); node must not be nil. If f returns true, Inspect
+// invokes f recursively for each of the non-nil children of node,
+// followed by a call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
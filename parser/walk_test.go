@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	tokens, err := NewScanner(`
+		fun f(a) {
+			if (a > 1) return -a;
+			print f(a + 1);
+		}
+	`).Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmts, err := New(tokens).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var visited []string
+	Inspect(stmts[0], func(n Node) bool {
+		if n != nil {
+			visited = append(visited, fmt.Sprintf("%T:%d", n, n.Line()))
+		}
+		// don't descend into the print statement
+		_, isPrint := n.(*PrintStmt)
+		return !isPrint
+	})
+
+	expected := []string{
+		"*parser.FunStmt:2",
+		"*parser.Block:2",
+		"*parser.IfStmt:3",
+		"*parser.BinaryExpr:3",
+		"*parser.Variable:3",
+		"*parser.LiteralExpr:3",
+		"*parser.ReturnStmt:3",
+		"*parser.UnaryExpr:3",
+		"*parser.Variable:3",
+		"*parser.PrintStmt:4",
+	}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("unexpected traversal\ngot:      %v\nexpected: %v", visited, expected)
+	}
+}
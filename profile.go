@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jrouviere/golox/interpreter"
+)
+
+func profileCmd(args []string) int {
+	flags := flag.NewFlagSet("profile", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text, collapsed or pprof")
+	output := flags.String("o", "", "write the profile to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "golox profile: expected one file")
+		return 2
+	}
+
+	p := interpreter.NewProfiler()
+	var write func(io.Writer) error
+	switch *format {
+	case "text":
+		write = p.WriteText
+	case "collapsed":
+		write = p.WriteCollapsed
+	case "pprof":
+		write = p.WritePprof
+	default:
+		fmt.Fprintf(os.Stderr, "golox profile: unknown format %q\n", *format)
+		return 2
+	}
+
+	path := flags.Arg(0)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	code := 0
+	interp := interpreter.New(
+		interpreter.WithProfiler(p),
+		interpreter.WithLoader(interpreter.FSLoader{FS: os.DirFS(filepath.Dir(path))}),
+	)
+	if err := interp.Exec(string(src)); err != nil {
+		// still report what ran until the error
+		fmt.Fprintln(os.Stderr, err)
+		code = 1
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := write(out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return code
+}